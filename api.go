@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"roofmail/client"
+	"roofmail/units"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prefix of the JSON API's routes
+const apiPrefix = "/api/v1"
+
+// Add the JSON API's routes. They're described by client.OpenAPISpec, which has to be updated
+// along with them.
+func registerAPI(router gin.IRouter) {
+	router.GET("/api/openapi.json", openAPIHandler)
+
+	v1 := router.Group(apiPrefix)
+	v1.GET("/forecast", apiForecastHandler)
+	v1.GET("/comfort", apiComfortHandler)
+	v1.GET("/locations", apiLocationsHandler)
+}
+
+// GET /api/openapi.json
+func openAPIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", client.OpenAPISpec)
+}
+
+// Respond with an API error and stop handling the request
+func abortWithAPIError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, client.ErrorResponse{
+		Error: &client.Error{Status: status, Code: code, Message: message},
+	})
+}
+
+// Respond with the API error matching a failure to get a forecast
+func abortWithForecastError(c *gin.Context, err error) {
+	infoLogger.Println("API error getting forecast:", err)
+
+	switch {
+	case errors.Is(err, wapi.ErrRateLimited):
+		abortWithAPIError(c, http.StatusServiceUnavailable, "rate_limited", "The weather provider is rate limiting requests, try again in a few minutes.")
+	case errors.Is(err, context.DeadlineExceeded):
+		abortWithAPIError(c, http.StatusGatewayTimeout, "upstream_timeout", "The weather provider took too long to respond.")
+	case errors.Is(err, wapi.ErrInvalidForecast):
+		abortWithAPIError(c, http.StatusBadGateway, "invalid_forecast", err.Error())
+	default:
+		abortWithAPIError(c, http.StatusBadGateway, "forecast_unavailable", forecastErrorMessage(err))
+	}
+}
+
+// Handle requests that don't match a route, with an API error for API routes
+func notFoundHandler(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		abortWithAPIError(c, http.StatusNotFound, "not_found", "No API route matches "+c.Request.URL.Path+".")
+		return
+	}
+
+	c.String(http.StatusNotFound, "404 page not found")
+}
+
+// Read the `units` query parameter, which picks the units of forecast text. Quantities are always
+// normalized, see client.Period.
+func apiQueryUnits(c *gin.Context) (units.System, bool) {
+	query, ok := c.GetQuery("units")
+	if !ok {
+		return displayUnits, true
+	}
+
+	system, err := units.ParseSystem(query)
+	if err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return 0, false
+	}
+
+	return system, true
+}
+
+// The location forecasts are served for
+func configuredLocation() client.Location {
+	location := client.Location{Latitude: LATITUDE, Longitude: LONGITUDE}
+
+	// LOCATION is only used when the coordinates weren't given directly
+	if os.Getenv("LATITUDE") == "" || os.Getenv("LONGITUDE") == "" {
+		location.Query = os.Getenv("LOCATION")
+	}
+
+	return location
+}
+
+func newAPIPeriod(period wapi.Period) client.Period {
+	conditions, err := period.Conditions()
+	if err != nil {
+		debugLogger.Printf("Reading conditions for period %d: %v", period.Number, err)
+	}
+
+	return client.Period{
+		Number:           period.Number,
+		Name:             period.Name,
+		StartTime:        period.StartTime,
+		EndTime:          period.EndTime,
+		IsDaytime:        period.IsDaytime,
+		ShortForecast:    period.ShortForecast,
+		DetailedForecast: period.DetailedForecast,
+		WindDirection:    period.WindDirection,
+		Icon:             period.Icon,
+		Conditions:       newAPIConditions(conditions),
+		Comfort:          assessComfort(conditions).String(),
+		Sources:          period.Sources,
+	}
+}
+
+// Convert a period's conditions for the API, which has them in °C, m/s and percent
+func newAPIConditions(conditions wapi.Conditions) client.Conditions {
+	return client.Conditions{
+		Temperature:                apiQuantity(conditions.Temperature),
+		Dewpoint:                   apiQuantity(conditions.Dewpoint),
+		RelativeHumidity:           apiQuantity(conditions.RelativeHumidity),
+		ProbabilityOfPrecipitation: apiQuantity(conditions.Precipitation),
+		WindSpeed:                  apiQuantity(conditions.WindSpeed),
+		WindGust:                   apiQuantity(conditions.WindGust),
+	}
+}
+
+// Get a quantity in the unit its type is stored in, or nil when it's missing
+func apiQuantity[T ~float64](value wapi.Optional[T]) *float64 {
+	quantity, ok := value.Get()
+	if !ok {
+		return nil
+	}
+
+	converted := float64(quantity)
+	return &converted
+}
+
+// GET /api/v1/forecast?type=daily|hourly&units=imperial|metric
+func apiForecastHandler(c *gin.Context) {
+	system, ok := apiQueryUnits(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	var result forecastResult
+	var err error
+
+	forecastType := c.DefaultQuery("type", "daily")
+	switch forecastType {
+	case "daily":
+		result, err = forecasts.Daily(ctx, apiUnits(system))
+	case "hourly":
+		result, err = forecasts.Hourly(ctx, apiUnits(system))
+	default:
+		abortWithAPIError(c, http.StatusBadRequest, "invalid_parameter", `type must be "daily" or "hourly"`)
+		return
+	}
+
+	if err != nil {
+		abortWithForecastError(c, err)
+		return
+	}
+
+	periods := make([]client.Period, 0, len(result.Forecast.Periods))
+	for _, period := range result.Forecast.Periods {
+		periods = append(periods, newAPIPeriod(period))
+	}
+
+	c.JSON(http.StatusOK, client.Forecast{
+		Location:    configuredLocation(),
+		Type:        forecastType,
+		Units:       system.String(),
+		GeneratedAt: result.Forecast.GeneratedAt,
+		FetchedAt:   result.FetchedAt,
+		Stale:       result.Stale,
+		Periods:     periods,
+	})
+}
+
+// GET /api/v1/comfort
+func apiComfortHandler(c *gin.Context) {
+	system, ok := apiQueryUnits(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	daily, err := forecasts.Daily(ctx, apiUnits(system))
+	if err != nil {
+		abortWithForecastError(c, err)
+		return
+	}
+
+	if len(daily.Forecast.Periods) == 0 {
+		abortWithAPIError(c, http.StatusBadGateway, "invalid_forecast", "The forecast has no periods.")
+		return
+	}
+
+	hourly, err := forecasts.Hourly(ctx, apiUnits(system))
+	if err != nil {
+		abortWithForecastError(c, err)
+		return
+	}
+
+	current := daily.Forecast.Periods[0]
+	period := newAPIPeriod(current)
+
+	// newAPIPeriod logs any error reading them
+	conditions, _ := current.Conditions()
+	factors := comfortFactors(conditions)
+
+	breakdown := make([]client.ComfortFactor, 0, len(factors))
+	for _, factor := range factors {
+		entry := client.ComfortFactor{Name: factor.Name, Known: factor.Known}
+		if factor.Known {
+			entry.Comfortable = &factor.Comfortable
+		}
+
+		breakdown = append(breakdown, entry)
+	}
+
+	c.JSON(http.StatusOK, client.Comfort{
+		Location:  configuredLocation(),
+		FetchedAt: daily.FetchedAt,
+		Stale:     daily.Stale || hourly.Stale,
+		Period:    period,
+		Comfort:   period.Comfort,
+		Score:     comfortScore(factors),
+		Breakdown: breakdown,
+		Windows:   comfortWindows(hourly.Forecast.Periods),
+	})
+}
+
+// Find the stretches of back-to-back periods with comfortable weather
+func comfortWindows(periods []wapi.Period) []client.ComfortWindow {
+	windows := []client.ComfortWindow{}
+	for _, period := range periods {
+		conditions, _ := period.Conditions()
+		if assessComfort(conditions) != comfortable {
+			continue
+		}
+
+		// extend the last window if this period continues it
+		last := len(windows) - 1
+		if last >= 0 && windows[last].End.Equal(period.StartTime) {
+			windows[last].End = period.EndTime
+			continue
+		}
+
+		windows = append(windows, client.ComfortWindow{Start: period.StartTime, End: period.EndTime})
+	}
+
+	return windows
+}
+
+// GET /api/v1/locations
+func apiLocationsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, client.Locations{
+		Locations: []client.Location{configuredLocation()},
+	})
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"roofmail/client"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Serve the API from a router backed by the given weather API
+func newAPITestRouter(api wapi.WeatherAPI) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	forecasts = newForecastStore(api)
+
+	router := gin.New()
+	registerAPI(router)
+	router.NoRoute(notFoundHandler)
+	return router
+}
+
+// Make a request and decode the JSON response
+func getJSON(t *testing.T, router http.Handler, target string, out any) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+	if got := recorder.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("GET %s Content-Type = %q, want JSON", target, got)
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+		t.Fatalf("GET %s: decoding %q: %v", target, recorder.Body.String(), err)
+	}
+
+	return recorder.Code
+}
+
+// A period starting at the given hour, with the temperature in °C and precipitation in percent
+func testPeriod(hour int, temperature float64, precipitation *float64) wapi.Period {
+	start := time.Date(2025, 6, 1, hour, 0, 0, 0, time.UTC)
+	period := wapi.Period{
+		Number:        hour + 1,
+		StartTime:     start,
+		EndTime:       start.Add(time.Hour),
+		ShortForecast: "Sunny",
+		Temperature:   &wapi.UnitValue{Value: temperature, UnitCode: "wmoUnit:degC"},
+		WindSpeed:     &wapi.WindSpeed{Value: floatPtr(1), UnitCode: "wmoUnit:m_s-1"},
+	}
+	if precipitation != nil {
+		period.ProbabilityOfPrecipitation = &wapi.UnitValue{Value: *precipitation, UnitCode: "wmoUnit:percent"}
+	}
+	return period
+}
+
+func TestAPIForecast(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	router := newAPITestRouter(&mockWeatherAPI{
+		dailyForecast:  wapi.DailyForecast{Periods: []wapi.Period{testPeriod(12, 30, nil)}},
+		hourlyForecast: wapi.HourlyForecast{Periods: []wapi.Period{testPeriod(12, 30, floatPtr(0)), testPeriod(13, 31, floatPtr(0))}},
+	})
+
+	var daily struct {
+		Type    string
+		Periods []struct {
+			Comfort    string
+			Conditions map[string]*float64
+		}
+	}
+	if code := getJSON(t, router, "/api/v1/forecast", &daily); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if daily.Type != "daily" || len(daily.Periods) != 1 {
+		t.Fatalf("unexpected daily forecast: %+v", daily)
+	}
+	period := daily.Periods[0]
+	if temperature := period.Conditions["temperature"]; temperature == nil || *temperature != 30 {
+		t.Errorf("temperature = %v, want 30", temperature)
+	}
+	if period.Conditions["probabilityOfPrecipitation"] != nil || period.Comfort != "unknown" {
+		t.Errorf("expected missing precipitation and unknown comfort, got %+v", period)
+	}
+
+	var hourly struct {
+		Type    string
+		Periods []json.RawMessage
+	}
+	getJSON(t, router, "/api/v1/forecast?type=hourly&units=metric", &hourly)
+	if hourly.Type != "hourly" || len(hourly.Periods) != 2 {
+		t.Errorf("unexpected hourly forecast: %+v", hourly)
+	}
+}
+
+func TestAPIComfort(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	router := newAPITestRouter(&mockWeatherAPI{
+		dailyForecast: wapi.DailyForecast{Periods: []wapi.Period{testPeriod(12, 30, nil)}},
+		hourlyForecast: wapi.HourlyForecast{Periods: []wapi.Period{
+			testPeriod(12, 30, floatPtr(0)),
+			testPeriod(13, 31, floatPtr(0)),
+			testPeriod(14, 31, floatPtr(80)),
+			testPeriod(15, 28, floatPtr(0)),
+		}},
+	})
+
+	var comfort client.Comfort
+	if code := getJSON(t, router, "/api/v1/comfort", &comfort); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	// precipitation is unknown, and doesn't count against the score
+	if comfort.Comfort != "unknown" || comfort.Score != 100 {
+		t.Errorf("comfort = %q, score = %d; want unknown, 100", comfort.Comfort, comfort.Score)
+	}
+	if len(comfort.Breakdown) != 3 || comfort.Breakdown[2].Known || comfort.Breakdown[2].Comfortable != nil {
+		t.Errorf("unexpected breakdown: %+v", comfort.Breakdown)
+	}
+	if len(comfort.Windows) != 2 || comfort.Windows[0].End.Hour() != 14 || comfort.Windows[1].Start.Hour() != 15 {
+		t.Errorf("unexpected windows: %+v", comfort.Windows)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	router := newAPITestRouter(&mockWeatherAPI{forecastErr: &wapi.APIError{Status: http.StatusTooManyRequests, Title: "Too Many Requests"}})
+
+	tests := []struct {
+		target string
+		status int
+		code   string
+	}{
+		{"/api/v1/forecast", http.StatusServiceUnavailable, "rate_limited"},
+		{"/api/v1/comfort", http.StatusServiceUnavailable, "rate_limited"},
+		{"/api/v1/forecast?type=weekly", http.StatusBadRequest, "invalid_parameter"},
+		{"/api/v1/forecast?units=furlongs", http.StatusBadRequest, "invalid_parameter"},
+		{"/api/v1/nothing", http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		var body client.ErrorResponse
+		code := getJSON(t, router, tt.target, &body)
+		if body.Error == nil {
+			t.Errorf("GET %s = %d, want an error envelope", tt.target, code)
+			continue
+		}
+		if code != tt.status || body.Error.Status != tt.status || body.Error.Code != tt.code || body.Error.Message == "" {
+			t.Errorf("GET %s = %d %+v, want %d %s", tt.target, code, body, tt.status, tt.code)
+		}
+	}
+}
+
+func TestAPILocations(t *testing.T) {
+	router := newAPITestRouter(&mockWeatherAPI{})
+
+	LATITUDE, LONGITUDE = 40.7, -74
+	defer func() { LATITUDE, LONGITUDE = 0, 0 }()
+
+	var body client.Locations
+	getJSON(t, router, "/api/v1/locations", &body)
+	if len(body.Locations) != 1 || body.Locations[0].Latitude != 40.7 || body.Locations[0].Longitude != -74 {
+		t.Errorf("unexpected locations: %+v", body)
+	}
+}
+
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	router := newAPITestRouter(&mockWeatherAPI{})
+
+	var spec struct {
+		OpenAPI string
+		Paths   map[string]map[string]json.RawMessage
+	}
+	if code := getJSON(t, router, "/api/openapi.json", &spec); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if spec.OpenAPI == "" {
+		t.Error("expected an OpenAPI version")
+	}
+
+	routes := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routes[strings.ToLower(route.Method)+" "+route.Path] = true
+		if _, ok := spec.Paths[route.Path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if !routes[method+" "+path] {
+				t.Errorf("OpenAPI document describes %s %s, which isn't routed", method, path)
+			}
+		}
+	}
+}
+
+// openAPISchemas checks JSON values against the schemas in an OpenAPI document. It understands the
+// parts of JSON Schema the document uses: $ref, type, nullable, enum, required, properties and
+// items.
+type openAPISchemas struct {
+	document map[string]any
+}
+
+// Follow a local reference like "#/components/schemas/Period"
+func (s openAPISchemas) resolve(schema map[string]any) map[string]any {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+
+		var node any = s.document
+		for _, name := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			node = node.(map[string]any)[name]
+		}
+		schema = node.(map[string]any)
+	}
+}
+
+// Find the schema for a response
+func (s openAPISchemas) response(path, method string, status int) (map[string]any, bool) {
+	operation, ok := s.document["paths"].(map[string]any)[path].(map[string]any)[method].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	response, ok := operation["responses"].(map[string]any)[strconv.Itoa(status)].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	content, ok := s.resolve(response)["content"].(map[string]any)["application/json"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	return s.resolve(content["schema"].(map[string]any)), true
+}
+
+// Check a value decoded with UseNumber against a schema, listing what doesn't match
+func (s openAPISchemas) validate(schema map[string]any, value any, at string) []string {
+	schema = s.resolve(schema)
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); !nullable {
+			return []string{at + " is null"}
+		}
+		return nil
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return []string{fmt.Sprintf("%s = %v, not one of %v", at, value, enum)}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{at + " isn't an object"}
+		}
+
+		// an object without properties is free-form
+		properties, ok := schema["properties"].(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		var problems []string
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s is missing required %s", at, name))
+			}
+		}
+		for name, field := range object {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s isn't in the schema", at, name))
+				continue
+			}
+			problems = append(problems, s.validate(property, field, at+"."+name)...)
+		}
+		return problems
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return []string{at + " isn't an array"}
+		}
+
+		var problems []string
+		for i, item := range array {
+			problems = append(problems, s.validate(schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+		return problems
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{at + " isn't a string"}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{at + " isn't a boolean"}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return []string{at + " isn't a number"}
+		}
+	case "integer":
+		if _, err := value.(json.Number).Int64(); err != nil {
+			return []string{at + " isn't an integer"}
+		}
+	}
+
+	return nil
+}
+
+func TestOpenAPISpecMatchesResponses(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	var document map[string]any
+	if err := json.Unmarshal(client.OpenAPISpec, &document); err != nil {
+		t.Fatal(err)
+	}
+	schemas := openAPISchemas{document: document}
+
+	// cover both known and missing values, so nullable fields are exercised
+	periods := []wapi.Period{
+		testPeriod(12, 30, floatPtr(0)),
+		testPeriod(13, 31, floatPtr(0)),
+		testPeriod(14, 31, nil),
+	}
+	ok := &mockWeatherAPI{
+		dailyForecast:  wapi.DailyForecast{Periods: periods},
+		hourlyForecast: wapi.HourlyForecast{Periods: periods},
+	}
+	failing := &mockWeatherAPI{forecastErr: errors.New("boom")}
+
+	tests := []struct {
+		api    wapi.WeatherAPI
+		path   string
+		query  string
+		status int
+	}{
+		{ok, "/api/v1/forecast", "", http.StatusOK},
+		{ok, "/api/v1/forecast", "?type=hourly&units=metric", http.StatusOK},
+		{ok, "/api/v1/comfort", "", http.StatusOK},
+		{ok, "/api/v1/locations", "", http.StatusOK},
+		{ok, "/api/openapi.json", "", http.StatusOK},
+		{ok, "/api/v1/forecast", "?units=furlongs", http.StatusBadRequest},
+		{failing, "/api/v1/forecast", "", http.StatusBadGateway},
+		{failing, "/api/v1/comfort", "", http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		newAPITestRouter(tt.api).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path+tt.query, nil))
+		if recorder.Code != tt.status {
+			t.Errorf("GET %s%s = %d, want %d", tt.path, tt.query, recorder.Code, tt.status)
+			continue
+		}
+
+		schema, ok := schemas.response(tt.path, "get", tt.status)
+		if !ok {
+			t.Errorf("OpenAPI document has no %d response for GET %s", tt.status, tt.path)
+			continue
+		}
+
+		decoder := json.NewDecoder(recorder.Body)
+		decoder.UseNumber()
+		var body any
+		if err := decoder.Decode(&body); err != nil {
+			t.Fatalf("GET %s%s: %v", tt.path, tt.query, err)
+		}
+
+		for _, problem := range schemas.validate(schema, body, "response") {
+			t.Errorf("GET %s%s: %s", tt.path, tt.query, problem)
+		}
+	}
+}
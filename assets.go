@@ -0,0 +1,77 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// The templates and static files, built into the binary so it runs from any directory
+//
+//go:embed templates static
+var embeddedAssets embed.FS
+
+// assets are the files the app serves pages from
+type assets struct {
+	Templates fs.FS // layout.html, partials/ and a template for each page
+	Static    fs.FS // served under /static
+	FromDisk  bool  // whether the files can change while running
+}
+
+// Serve the static files. Their hashes are remembered unless they're on disk, where they can change
+// while running.
+func (a assets) staticFiles() *staticFiles {
+	return newStaticFiles(a.Static, !a.FromDisk)
+}
+
+// Command line options for serving the app
+type serveFlags struct {
+	AssetsDir string
+}
+
+// Parse the command line options for serving the app
+func parseServeFlags(args []string) (serveFlags, error) {
+	var parsed serveFlags
+
+	flags := flag.NewFlagSet("roofmail", flag.ContinueOnError)
+	flags.StringVar(&parsed.AssetsDir, "assets", "",
+		"directory holding templates/ and static/ to serve instead of the embedded ones; "+
+			"defaults to the working directory when APP_ENV=development")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return serveFlags{}, err
+	}
+
+	return parsed, nil
+}
+
+// Load the templates and static files from dir, or the embedded ones when dir is empty
+func loadAssets(dir string) (assets, error) {
+	root := fs.FS(embeddedAssets)
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return assets{}, fmt.Errorf("reading assets directory: %w", err)
+		}
+		if !info.IsDir() {
+			return assets{}, fmt.Errorf("assets directory %s is not a directory", dir)
+		}
+
+		root = os.DirFS(dir)
+	}
+
+	templates, err := fs.Sub(root, "templates")
+	if err != nil {
+		return assets{}, err
+	}
+
+	static, err := fs.Sub(root, "static")
+	if err != nil {
+		return assets{}, err
+	}
+
+	return assets{Templates: templates, Static: static, FromDisk: dir != ""}, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseServeFlags(t *testing.T) {
+	flags, err := parseServeFlags(nil)
+	if err != nil || flags.AssetsDir != "" {
+		t.Errorf("parseServeFlags(nil) = %+v, %v, want the embedded assets", flags, err)
+	}
+
+	flags, err = parseServeFlags([]string{"-assets", "/srv/roofmail"})
+	if err != nil || flags.AssetsDir != "/srv/roofmail" {
+		t.Errorf("parseServeFlags(-assets) = %+v, %v", flags, err)
+	}
+
+	_, err = parseServeFlags([]string{"-nope"})
+	if err == nil {
+		t.Error("expected an error for an unknown flag")
+	}
+}
+
+func TestLoadAssets_Embedded(t *testing.T) {
+	files, err := loadAssets("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files.FromDisk {
+		t.Error("embedded assets shouldn't be from disk")
+	}
+
+	for _, name := range []string{"layout.html", "partials/nav.html", "index.html", "hourly.html", "week.html"} {
+		if _, err := fs.Stat(files.Templates, name); err != nil {
+			t.Errorf("template %s isn't embedded: %v", name, err)
+		}
+	}
+	for _, name := range []string{"favicon.ico", "css/styles.css", "js/script.js"} {
+		if _, err := fs.Stat(files.Static, name); err != nil {
+			t.Errorf("static file %s isn't embedded: %v", name, err)
+		}
+	}
+}
+
+func TestLoadAssets_FromDisk(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "static", "css"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "static", "css", "styles.css"), []byte("body {}"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := loadAssets(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !files.FromDisk {
+		t.Error("expected assets from disk")
+	}
+
+	data, err := fs.ReadFile(files.Static, "css/styles.css")
+	if err != nil || string(data) != "body {}" {
+		t.Errorf("styles.css = %q, %v, want the file on disk", data, err)
+	}
+
+	_, err = loadAssets(filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+
+	_, err = loadAssets(filepath.Join(dir, "static", "css", "styles.css"))
+	if err == nil {
+		t.Error("expected an error for a file")
+	}
+}
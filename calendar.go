@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"roofmail/client"
+	"roofmail/units"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Format of UTC date-times in iCalendar (RFC 5545)
+const icalTimeFormat = "20060102T150405Z"
+
+// Longest a content line can be, in octets, before it has to be folded
+const icalLineLength = 75
+
+// Build an iCalendar feed with an event for each upcoming comfort window in the hourly periods, and
+// a cancelled event for each upcoming window the history saw dropped from a forecast.
+//
+// Event UIDs are made from the location and the history's ID for the window, which follows the
+// window as new forecasts shift it, so calendar apps update the event rather than adding another
+// one. Windows that appear get new UIDs, even in the same part of the day as another.
+func buildCalendar(periods []wapi.Period, history *comfortHistory, location client.Location, now time.Time, system units.System) string {
+	var b strings.Builder
+
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//Roofmail//Comfort windows//EN")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:Roofmail")
+	writeICalLine(&b, "X-WR-CALDESC:"+icalText("Good roof time: when the weather is comfortable enough to sit outside."))
+	writeICalLine(&b, "REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	writeICalLine(&b, "X-PUBLISHED-TTL:PT1H")
+
+	for _, window := range history.Identify(upcomingWindows(comfortWindows(periods), now)) {
+		writeICalEvent(&b, window, location, now, "CONFIRMED", describeCalendarWindow(periods, window.Window, system))
+	}
+
+	for _, window := range history.Cancelled(now) {
+		writeICalEvent(&b, window, location, now, "CANCELLED", "No longer comfortable in the latest forecast.")
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+
+	return b.String()
+}
+
+// Write the event for a comfort window
+func writeICalEvent(b *strings.Builder, window trackedWindow, location client.Location, now time.Time, status, description string) {
+	writeICalLine(b, "BEGIN:VEVENT")
+	writeICalLine(b, fmt.Sprintf("UID:%s-%.4f,%.4f@roofmail", window.ID, location.Latitude, location.Longitude))
+	writeICalLine(b, "DTSTAMP:"+now.UTC().Format(icalTimeFormat))
+	writeICalLine(b, "DTSTART:"+window.Window.Start.UTC().Format(icalTimeFormat))
+	writeICalLine(b, "DTEND:"+window.Window.End.UTC().Format(icalTimeFormat))
+	writeICalLine(b, "SUMMARY:"+icalText("Good roof time"))
+	writeICalLine(b, "DESCRIPTION:"+icalText(description))
+	writeICalLine(b, fmt.Sprintf("GEO:%.4f;%.4f", location.Latitude, location.Longitude))
+	writeICalLine(b, "STATUS:"+status)
+	writeICalLine(b, "TRANSP:TRANSPARENT")
+	writeICalLine(b, "END:VEVENT")
+}
+
+// Describe the temperatures during a comfort window, e.g. "Comfortable weather, 78°F to 84°F."
+func describeCalendarWindow(periods []wapi.Period, window client.ComfortWindow, system units.System) string {
+	var low, high units.Temperature
+	var found bool
+	for _, period := range periods {
+		if period.StartTime.Before(window.Start) || period.EndTime.After(window.End) {
+			continue
+		}
+
+		conditions, _ := period.Conditions()
+		temperature, ok := conditions.Temperature.Get()
+		if !ok {
+			continue
+		}
+
+		if !found || temperature < low {
+			low = temperature
+		}
+		if !found || temperature > high {
+			high = temperature
+		}
+		found = true
+	}
+
+	switch {
+	case !found:
+		return "Comfortable weather."
+	case low.Format(system) == high.Format(system):
+		return fmt.Sprintf("Comfortable weather, %s.", high.Format(system))
+	default:
+		return fmt.Sprintf("Comfortable weather, %s to %s.", low.Format(system), high.Format(system))
+	}
+}
+
+// Escape a TEXT value
+func icalText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// Write a content line, folding it onto continuation lines so none is longer than icalLineLength
+// octets. Lines are only split between characters, never inside one.
+func writeICalLine(b *strings.Builder, line string) {
+	limit := icalLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+
+		// continuation lines start with a space, which counts towards their length
+		limit = icalLineLength - 1
+	}
+
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// GET /calendar.ics
+func calendarHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// calendar apps ignore cookies, so the units aren't remembered
+	system := readDisplayUnits(c)
+
+	result, err := forecasts.Hourly(ctx, apiUnits(system))
+	if err != nil {
+		infoLogger.Println("Error getting hourly forecast for calendar:", err)
+		c.String(http.StatusServiceUnavailable, forecastErrorMessage(err))
+		return
+	}
+
+	calendar := buildCalendar(result.Forecast.Periods, windowHistory, configuredLocation(), time.Now(), system)
+
+	c.Header("Content-Disposition", `inline; filename="roofmail.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
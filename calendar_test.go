@@ -0,0 +1,175 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"roofmail/client"
+	"roofmail/units"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Hourly periods on June 1st 2025, comfortable only during the given [start, end) hours
+func comfortableBetween(hours ...[2]int) []wapi.Period {
+	var uncomfortable []int
+	for hour := 0; hour < 24; hour++ {
+		comfortable := false
+		for _, window := range hours {
+			comfortable = comfortable || (hour >= window[0] && hour < window[1])
+		}
+		if !comfortable {
+			uncomfortable = append(uncomfortable, hour)
+		}
+	}
+
+	return testHourlyPeriods(24, uncomfortable...)
+}
+
+// Find the UID of the event starting at the given hour on June 1st 2025
+func eventUID(calendar string, hour int) string {
+	start := testWindow(hour, hour).Start.Format(icalTimeFormat)
+	for _, event := range strings.Split(calendar, "BEGIN:VEVENT")[1:] {
+		if strings.Contains(event, "DTSTART:"+start) {
+			return regexp.MustCompile(`UID:(\S+)`).FindStringSubmatch(event)[1]
+		}
+	}
+
+	return ""
+}
+
+func TestBuildCalendar(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	location := client.Location{Latitude: 40, Longitude: -75}
+	now := time.Date(2025, 6, 1, 1, 30, 0, 0, time.UTC)
+	history := &comfortHistory{}
+
+	// comfortable until noon, then from 2 PM on; the first window is partly over
+	periods := testHourlyPeriods(24, 12, 13)
+	periods[16].Temperature.Value = 25
+
+	calendar := buildCalendar(periods, history, location, now, units.Imperial)
+	if !strings.HasPrefix(calendar, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(calendar, "END:VCALENDAR\r\n") {
+		t.Errorf("unexpected calendar framing: %q", calendar)
+	}
+	if strings.Contains(strings.ReplaceAll(calendar, "\r\n", ""), "\n") {
+		t.Error("expected every line to end in CRLF")
+	}
+
+	uids := regexp.MustCompile(`UID:(\S+)`).FindAllStringSubmatch(calendar, -1)
+	if len(uids) != 2 || uids[0][1] != "20250601T0000Z-40.0000,-75.0000@roofmail" || uids[1][1] != "20250601T1400Z-40.0000,-75.0000@roofmail" {
+		t.Errorf("unexpected UIDs: %v", uids)
+	}
+	if !strings.Contains(calendar, "DTSTART:20250601T000000Z\r\nDTEND:20250601T120000Z\r\n") {
+		t.Errorf("expected the first window's times, got %q", calendar)
+	}
+	if !strings.Contains(calendar, `DESCRIPTION:Comfortable weather\, 77°F to 86°F.`) || strings.Contains(calendar, "STATUS:CANCELLED") {
+		t.Errorf("expected an escaped temperature range, got %q", calendar)
+	}
+
+	// past windows are left out
+	later := buildCalendar(periods, history, location, now.Add(12*time.Hour), units.Imperial)
+	if strings.Count(later, "BEGIN:VEVENT") != 1 {
+		t.Errorf("expected only the afternoon window, got %q", later)
+	}
+}
+
+func TestBuildCalendar_UIDs(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	location := client.Location{Latitude: 40, Longitude: -75}
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	history := &comfortHistory{}
+
+	// two windows in the afternoon are two events
+	periods := comfortableBetween([2]int{13, 15}, [2]int{16, 20})
+	calendar := buildCalendar(periods, history, location, now, units.Imperial)
+	early, late := eventUID(calendar, 13), eventUID(calendar, 16)
+	if early == "" || late == "" || early == late {
+		t.Fatalf("expected two UIDs, got %q and %q in %q", early, late, calendar)
+	}
+	history.Record(comfortWindows(periods), now)
+
+	// the later window shifts an hour, and keeps its UID
+	periods = comfortableBetween([2]int{13, 15}, [2]int{17, 21})
+	calendar = buildCalendar(periods, history, location, now, units.Imperial)
+	if got := eventUID(calendar, 17); got != late {
+		t.Errorf("shifted window UID = %q, want %q", got, late)
+	}
+	history.Record(comfortWindows(periods), now)
+
+	// the early window is cancelled and the later one moves into its slot; it keeps its UID rather
+	// than taking over the early one's, which is cancelled
+	periods = comfortableBetween([2]int{14, 19})
+	history.Record(comfortWindows(periods), now)
+	calendar = buildCalendar(periods, history, location, now, units.Imperial)
+	if got := eventUID(calendar, 14); got != late {
+		t.Errorf("moved window UID = %q, want %q", got, late)
+	}
+	cancelled := strings.Split(calendar, "BEGIN:VEVENT")
+	if len(cancelled) != 3 || !strings.Contains(cancelled[2], "UID:"+early) || !strings.Contains(cancelled[2], "STATUS:CANCELLED") {
+		t.Errorf("expected the early window's event cancelled, got %q", calendar)
+	}
+
+	// cancelled windows are dropped once they would have ended
+	if later := buildCalendar(nil, history, location, now.Add(22*time.Hour), units.Imperial); strings.Contains(later, "BEGIN:VEVENT") {
+		t.Errorf("expected no events, got %q", later)
+	}
+}
+
+func TestCalendarHandler(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+	gin.SetMode(gin.TestMode)
+
+	windowHistory = &comfortHistory{}
+	forecasts = newForecastStore(&mockWeatherAPI{hourlyForecast: wapi.HourlyForecast{Periods: testHourlyPeriods(24)}})
+
+	router := gin.New()
+	router.GET("/calendar.ics", calendarHandler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/calendar.ics?units=metric", nil))
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("status = %d, Content-Type %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if cookie := recorder.Header().Get("Set-Cookie"); cookie != "" {
+		t.Errorf("expected no units cookie, got %q", cookie)
+	}
+}
+
+func TestWriteICalLine(t *testing.T) {
+	var b strings.Builder
+	line := "DESCRIPTION:" + strings.Repeat("°", 60)
+	writeICalLine(&b, line)
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected the line to be folded, got %q", b.String())
+	}
+	for i, folded := range lines {
+		if len(folded) > icalLineLength {
+			t.Errorf("line %d is %d octets long", i, len(folded))
+		}
+		if i > 0 && !strings.HasPrefix(folded, " ") {
+			t.Errorf("continuation line %d doesn't start with a space", i)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(b.String(), "\r\n ", "")
+	if unfolded != line+"\r\n" {
+		t.Errorf("unfolding gave %q, want %q", unfolded, line)
+	}
+
+	if got := icalText("Hot; windy, wet\\dry\nlater"); got != `Hot\; windy\, wet\\dry\nlater` {
+		t.Errorf("icalText() = %q", got)
+	}
+}
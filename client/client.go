@@ -0,0 +1,154 @@
+// Package client reads forecasts and comfort evaluations from Roofmail's JSON API.
+package client
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"roofmail/units"
+)
+
+// OpenAPISpec is the OpenAPI 3 document describing the API, which the server publishes at
+// /api/openapi.json.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// Client makes requests against a Roofmail server.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	userAgent  string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with. http.DefaultClient is used otherwise.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent requests are sent with.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New creates a client for the Roofmail server at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// RequestOption sets a query parameter on a request.
+type RequestOption func(url.Values)
+
+// Hourly asks for the hourly forecast instead of the daily one.
+func Hourly() RequestOption {
+	return func(query url.Values) {
+		query.Set("type", "hourly")
+	}
+}
+
+// WithUnits sets the units used in forecast text. Quantities are always normalized.
+func WithUnits(system units.System) RequestOption {
+	return func(query url.Values) {
+		query.Set("units", system.String())
+	}
+}
+
+// Forecast gets the forecast, daily unless Hourly is given.
+func (c *Client) Forecast(ctx context.Context, opts ...RequestOption) (Forecast, error) {
+	var forecast Forecast
+	err := c.get(ctx, "/api/v1/forecast", opts, &forecast)
+	return forecast, err
+}
+
+// Comfort gets whether the weather is comfortable, with a breakdown and the comfortable windows
+// coming up.
+func (c *Client) Comfort(ctx context.Context, opts ...RequestOption) (Comfort, error) {
+	var comfort Comfort
+	err := c.get(ctx, "/api/v1/comfort", opts, &comfort)
+	return comfort, err
+}
+
+// Locations gets the locations the server forecasts for.
+func (c *Client) Locations(ctx context.Context) ([]Location, error) {
+	var locations Locations
+	err := c.get(ctx, "/api/v1/locations", nil, &locations)
+	return locations.Locations, err
+}
+
+// Make a GET request and decode the response into out. Error responses are returned as *Error.
+func (c *Client) get(ctx context.Context, path string, opts []RequestOption, out any) error {
+	query := url.Values{}
+	for _, opt := range opts {
+		opt(query)
+	}
+
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Accept", "application/json")
+	if c.userAgent != "" {
+		request.Header.Set("User-Agent", c.userAgent)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return readError(response.StatusCode, body)
+	}
+
+	err = json.Unmarshal(body, out)
+	if err != nil {
+		return fmt.Errorf("decoding %s response: %w", path, err)
+	}
+
+	return nil
+}
+
+// Read an error envelope, falling back to the status when the body isn't one
+func readError(status int, body []byte) error {
+	var envelope ErrorResponse
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error != nil {
+		return envelope.Error
+	}
+
+	return &Error{Status: status, Code: "unknown", Message: http.StatusText(status)}
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"roofmail/units"
+)
+
+func TestClient_Forecast(t *testing.T) {
+	var gotQuery, gotAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/forecast" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		gotQuery = r.URL.RawQuery
+		gotAgent = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"type":"hourly","units":"metric","periods":[{"number":1,"conditions":{"temperature":21.5,"windSpeed":null},"comfort":"unknown"}]}`))
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", WithHTTPClient(server.Client()), WithUserAgent("dashboard"))
+	forecast, err := c.Forecast(context.Background(), Hourly(), WithUnits(units.Metric))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotQuery != "type=hourly&units=metric" || gotAgent != "dashboard" {
+		t.Errorf("unexpected request: query %q, User-Agent %q", gotQuery, gotAgent)
+	}
+	if forecast.Type != "hourly" || len(forecast.Periods) != 1 {
+		t.Fatalf("unexpected forecast: %+v", forecast)
+	}
+	conditions := forecast.Periods[0].Conditions
+	if conditions.Temperature == nil || *conditions.Temperature != 21.5 || conditions.WindSpeed != nil {
+		t.Errorf("unexpected conditions: %+v", conditions)
+	}
+}
+
+func TestClient_ComfortAndLocations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/comfort":
+			w.Write([]byte(`{"comfort":"comfortable","score":100,"breakdown":[{"name":"wind","known":true,"comfortable":true}],"windows":[]}`))
+		case "/api/v1/locations":
+			w.Write([]byte(`{"locations":[{"latitude":40,"longitude":-75,"query":"19103"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithHTTPClient(server.Client()))
+	comfort, err := c.Comfort(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if comfort.Comfort != "comfortable" || comfort.Score != 100 || len(comfort.Breakdown) != 1 || !*comfort.Breakdown[0].Comfortable {
+		t.Errorf("unexpected comfort: %+v", comfort)
+	}
+
+	locations, err := c.Locations(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(locations) != 1 || locations[0].Query != "19103" {
+		t.Errorf("unexpected locations: %+v", locations)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/comfort" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"status":503,"code":"rate_limited","message":"slow down"}}`))
+			return
+		}
+		http.Error(w, "gateway exploded", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithHTTPClient(server.Client()))
+
+	var apiErr *Error
+	_, err := c.Comfort(context.Background())
+	if !errors.As(err, &apiErr) || apiErr.Code != "rate_limited" || apiErr.Message != "slow down" {
+		t.Errorf("expected rate_limited error, got %v", err)
+	}
+
+	// responses that aren't an error envelope still become an *Error
+	_, err = c.Forecast(context.Background())
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway || apiErr.Code != "unknown" {
+		t.Errorf("expected unknown error with the status, got %v", err)
+	}
+}
+
+// The schemas in the OpenAPI document have to describe the types' JSON
+func TestOpenAPISpecMatchesTypes(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage
+			}
+		}
+	}
+	if err := json.Unmarshal(OpenAPISpec, &spec); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+
+	types := map[string]any{
+		"Location":      Location{},
+		"Locations":     Locations{},
+		"Conditions":    Conditions{},
+		"Period":        Period{},
+		"Forecast":      Forecast{},
+		"ComfortFactor": ComfortFactor{},
+		"ComfortWindow": ComfortWindow{},
+		"Comfort":       Comfort{},
+		"Error":         Error{},
+		"ErrorResponse": ErrorResponse{},
+	}
+	for name, value := range types {
+		schema, ok := spec.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is missing", name)
+			continue
+		}
+
+		var described []string
+		for property := range schema.Properties {
+			described = append(described, property)
+		}
+
+		if got, want := sorted(described), jsonFields(reflect.TypeOf(value)); !reflect.DeepEqual(got, want) {
+			t.Errorf("schema %s has properties %v, want %v", name, got, want)
+		}
+	}
+}
+
+// Get the JSON names of a struct's fields
+func jsonFields(structType reflect.Type) []string {
+	var names []string
+	for i := 0; i < structType.NumField(); i++ {
+		name, _, _ := strings.Cut(structType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	return sorted(names)
+}
+
+func sorted(values []string) []string {
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// Location is a place forecasts are served for.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Query     string  `json:"query,omitempty"` // The LOCATION the coordinates were geocoded from
+}
+
+// Conditions is the weather for a period, normalized to °C, m/s and percent. Values the forecast
+// doesn't include are nil.
+type Conditions struct {
+	Temperature                *float64 `json:"temperature"`      // °C
+	Dewpoint                   *float64 `json:"dewpoint"`         // °C
+	RelativeHumidity           *float64 `json:"relativeHumidity"` // percent
+	ProbabilityOfPrecipitation *float64 `json:"probabilityOfPrecipitation"`
+	WindSpeed                  *float64 `json:"windSpeed"` // m/s
+	WindGust                   *float64 `json:"windGust"`  // m/s
+}
+
+// Period is a forecast period.
+type Period struct {
+	Number           int        `json:"number"`
+	Name             string     `json:"name,omitempty"`
+	StartTime        time.Time  `json:"startTime"`
+	EndTime          time.Time  `json:"endTime"`
+	IsDaytime        bool       `json:"isDaytime"`
+	ShortForecast    string     `json:"shortForecast"`
+	DetailedForecast string     `json:"detailedForecast,omitempty"`
+	WindDirection    string     `json:"windDirection,omitempty"`
+	Icon             string     `json:"icon,omitempty"`
+	Conditions       Conditions `json:"conditions"`
+	Comfort          string     `json:"comfort"` // "comfortable", "uncomfortable" or "unknown"
+	Sources          []string   `json:"sources,omitempty"`
+}
+
+// Forecast is the response from GET /api/v1/forecast.
+type Forecast struct {
+	Location    Location  `json:"location"`
+	Type        string    `json:"type"`  // "daily" or "hourly"
+	Units       string    `json:"units"` // Units used in forecast text
+	GeneratedAt time.Time `json:"generatedAt"`
+	FetchedAt   time.Time `json:"fetchedAt"`
+	Stale       bool      `json:"stale"`
+	Periods     []Period  `json:"periods"`
+}
+
+// ComfortFactor is one of the values that decide whether the weather is comfortable.
+type ComfortFactor struct {
+	Name        string `json:"name"`
+	Known       bool   `json:"known"`
+	Comfortable *bool  `json:"comfortable"` // nil when the value is unknown
+}
+
+// ComfortWindow is a stretch of time with comfortable weather.
+type ComfortWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Comfort is the response from GET /api/v1/comfort.
+type Comfort struct {
+	Location  Location  `json:"location"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Stale     bool      `json:"stale"`
+	Period    Period    `json:"period"`
+	Comfort   string    `json:"comfort"`
+	// Score is the percentage of known factors in their comfortable range, or 0 if none are known
+	Score     int             `json:"score"`
+	Breakdown []ComfortFactor `json:"breakdown"`
+	Windows   []ComfortWindow `json:"windows"` // Comfortable stretches in the hourly forecast
+}
+
+// Locations is the response from GET /api/v1/locations.
+type Locations struct {
+	Locations []Location `json:"locations"`
+}
+
+// ErrorResponse is the envelope every API error is returned in.
+type ErrorResponse struct {
+	Error *Error `json:"error"`
+}
+
+// Error is an error returned by the API.
+type Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("roofmail API error %d (%s): %s", e.Status, e.Code, e.Message)
+}
@@ -0,0 +1,381 @@
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"roofmail/httpfixture"
+)
+
+// Scenario selects the weather, or failure, the fake server reports.
+type Scenario string
+
+const (
+	Normal   Scenario = "normal"   // the recorded forecasts as they are
+	HeatWave Scenario = "heatwave" // temperatures well above normal, with an excessive heat warning
+	Storm    Scenario = "storm"    // heavy rain and strong winds, with a severe thunderstorm warning
+	Outage   Scenario = "outage"   // every request fails with a 503
+	Slow     Scenario = "slow"     // the recorded forecasts, after a delay
+)
+
+// Scenarios lists every supported scenario
+var Scenarios = []Scenario{Normal, HeatWave, Storm, Outage, Slow}
+
+// DefaultSlowDelay is how long the Slow scenario waits when no delay is configured
+var DefaultSlowDelay = 5 * time.Second
+
+// Options configures the fake server.
+type Options struct {
+	Scenario Scenario
+	// Delay is added before every response. The Slow scenario uses DefaultSlowDelay when unset.
+	Delay time.Duration
+	// FixturesDir holds the recorded daily and hourly forecasts the responses are built from.
+	FixturesDir string
+	// Now returns the current time; forecast periods are shifted to start at the current hour.
+	Now func() time.Time
+}
+
+// server is a fake of the weather.gov endpoints Roofmail uses
+type server struct {
+	options Options
+	daily   map[string]any
+	hourly  map[string]any
+}
+
+// NewHandler creates a handler serving fake weather.gov endpoints:
+//
+//	/points/{latitude},{longitude}
+//	/gridpoints/{office}/{x},{y}/forecast
+//	/gridpoints/{office}/{x},{y}/forecast/hourly
+//	/gridpoints/{office}/{x},{y}/stations
+//	/stations
+//	/alerts/active
+func NewHandler(options Options) (http.Handler, error) {
+	if options.Scenario == "" {
+		options.Scenario = Normal
+	}
+	if !isScenario(options.Scenario) {
+		return nil, fmt.Errorf("unknown scenario %q", options.Scenario)
+	}
+	if options.Scenario == Slow && options.Delay == 0 {
+		options.Delay = DefaultSlowDelay
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	daily, hourly, err := loadForecasts(options.FixturesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &server{options: options, daily: daily, hourly: hourly}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /points/{point}", s.points)
+	mux.HandleFunc("GET /gridpoints/{office}/{xy}/forecast", s.forecast(s.daily))
+	mux.HandleFunc("GET /gridpoints/{office}/{xy}/forecast/hourly", s.forecast(s.hourly))
+	mux.HandleFunc("GET /gridpoints/{office}/{xy}/stations", s.stations)
+	mux.HandleFunc("GET /stations", s.stations)
+	mux.HandleFunc("GET /alerts/active", s.alerts)
+
+	return s.middleware(mux), nil
+}
+
+// Start runs a fake server on a local port, for tests. Close it when done.
+func Start(options Options) (*httptest.Server, error) {
+	handler, err := NewHandler(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return httptest.NewServer(handler), nil
+}
+
+func isScenario(scenario Scenario) bool {
+	for _, s := range Scenarios {
+		if s == scenario {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Find the recorded daily and hourly forecasts in a fixtures directory
+func loadForecasts(dir string) (map[string]any, map[string]any, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var daily, hourly map[string]any
+	for _, path := range paths {
+		fixture, err := httpfixture.Load(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if fixture.Response.StatusCode != http.StatusOK || len(fixture.Response.Body) == 0 {
+			continue
+		}
+
+		url := strings.SplitN(fixture.Request.URL, "?", 2)[0]
+		switch {
+		case hourly == nil && strings.HasSuffix(url, "/forecast/hourly"):
+			err = json.Unmarshal(fixture.Response.Body, &hourly)
+		case daily == nil && strings.HasSuffix(url, "/forecast"):
+			err = json.Unmarshal(fixture.Response.Body, &daily)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+
+	if daily == nil || hourly == nil {
+		return nil, nil, fmt.Errorf("no daily and hourly forecast fixtures in %s", dir)
+	}
+
+	return daily, hourly, nil
+}
+
+// Apply the delay and outage scenarios to every request
+func (s *server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.options.Delay > 0 {
+			timer := time.NewTimer(s.options.Delay)
+			select {
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		if s.options.Scenario == Outage {
+			writeProblem(w, http.StatusServiceUnavailable, "Service Unavailable", "The fake weather.gov API is simulating an outage.")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Write a problem+json error, like weather.gov does
+func writeProblem(w http.ResponseWriter, status int, title, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"type":          "https://api.weather.gov/problems/" + strings.ReplaceAll(title, " ", ""),
+		"title":         title,
+		"status":        status,
+		"detail":        detail,
+		"correlationId": "fakeapi",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Build an absolute URL on this server
+func baseURL(r *http.Request) string {
+	return "http://" + r.Host
+}
+
+func (s *server) points(w http.ResponseWriter, r *http.Request) {
+	var latitude, longitude float64
+	_, err := fmt.Sscanf(r.PathValue("point"), "%f,%f", &latitude, &longitude)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "Invalid Parameter", "Parameter \"point\" is invalid")
+		return
+	}
+
+	grid := baseURL(r) + "/gridpoints/FAKE/1,1"
+	writeJSON(w, map[string]any{
+		"type": "Feature",
+		"geometry": map[string]any{
+			"type":        "Point",
+			"coordinates": []float64{longitude, latitude},
+		},
+		"properties": map[string]any{
+			"@id":                 baseURL(r) + r.URL.Path,
+			"@type":               "wx:Point",
+			"cwa":                 "FAKE",
+			"forecastOffice":      baseURL(r) + "/offices/FAKE",
+			"gridId":              "FAKE",
+			"gridX":               1,
+			"gridY":               1,
+			"forecast":            grid + "/forecast",
+			"forecastHourly":      grid + "/forecast/hourly",
+			"forecastGridData":    grid,
+			"observationStations": grid + "/stations",
+		},
+	})
+}
+
+// Serve a forecast, adjusted for the scenario
+func (s *server) forecast(base map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		forecast := deepCopy(base)
+		properties, _ := forecast["properties"].(map[string]any)
+		periods, _ := properties["periods"].([]any)
+
+		now := s.options.Now()
+		shiftPeriods(periods, now.Truncate(time.Hour))
+		properties["generatedAt"] = now.UTC().Format(time.RFC3339)
+		properties["updateTime"] = now.UTC().Format(time.RFC3339)
+
+		for _, period := range periods {
+			if period, ok := period.(map[string]any); ok {
+				applyScenario(period, s.options.Scenario)
+			}
+		}
+
+		writeJSON(w, forecast)
+	}
+}
+
+func (s *server) stations(w http.ResponseWriter, r *http.Request) {
+	station := baseURL(r) + "/stations/KFAK"
+	writeJSON(w, map[string]any{
+		"type": "FeatureCollection",
+		"features": []any{
+			map[string]any{
+				"id":   station,
+				"type": "Feature",
+				"properties": map[string]any{
+					"@id":               station,
+					"stationIdentifier": "KFAK",
+					"name":              "Fake Station",
+					"timeZone":          "America/New_York",
+				},
+			},
+		},
+		"observationStations": []string{station},
+	})
+}
+
+func (s *server) alerts(w http.ResponseWriter, r *http.Request) {
+	features := []any{}
+
+	now := s.options.Now().UTC()
+	alert := func(event, severity, headline string) map[string]any {
+		return map[string]any{
+			"id":   baseURL(r) + "/alerts/fake-" + strings.ToLower(strings.ReplaceAll(event, " ", "-")),
+			"type": "Feature",
+			"properties": map[string]any{
+				"event":     event,
+				"severity":  severity,
+				"certainty": "Likely",
+				"urgency":   "Expected",
+				"headline":  headline,
+				"onset":     now.Format(time.RFC3339),
+				"expires":   now.Add(12 * time.Hour).Format(time.RFC3339),
+				"status":    "Actual",
+			},
+		}
+	}
+
+	switch s.options.Scenario {
+	case HeatWave:
+		features = append(features, alert("Excessive Heat Warning", "Severe", "Excessive Heat Warning issued by the fake weather.gov API"))
+	case Storm:
+		features = append(features, alert("Severe Thunderstorm Warning", "Severe", "Severe Thunderstorm Warning issued by the fake weather.gov API"))
+	}
+
+	writeJSON(w, map[string]any{
+		"type":     "FeatureCollection",
+		"title":    "Current watches, warnings, and advisories",
+		"updated":  now.Format(time.RFC3339),
+		"features": features,
+	})
+}
+
+// Move the periods so the first one starts at `start`, keeping their spacing
+func shiftPeriods(periods []any, start time.Time) {
+	if len(periods) == 0 {
+		return
+	}
+
+	first, _ := periods[0].(map[string]any)
+	firstStart, err := time.Parse(time.RFC3339, fmt.Sprint(first["startTime"]))
+	if err != nil {
+		return
+	}
+
+	offset := start.Sub(firstStart)
+	for _, period := range periods {
+		period, ok := period.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		for _, key := range []string{"startTime", "endTime"} {
+			t, err := time.Parse(time.RFC3339, fmt.Sprint(period[key]))
+			if err == nil {
+				period[key] = t.Add(offset).In(firstStart.Location()).Format(time.RFC3339)
+			}
+		}
+	}
+}
+
+// Adjust a period's weather for the scenario
+func applyScenario(period map[string]any, scenario Scenario) {
+	switch scenario {
+	case HeatWave:
+		// 12C is roughly 22F
+		addToValue(period["temperature"], 12, 22)
+		setValue(period["probabilityOfPrecipitation"], 0)
+		period["shortForecast"] = "Hot"
+		period["detailedForecast"] = "Dangerously hot. An excessive heat warning is in effect."
+	case Storm:
+		setValue(period["probabilityOfPrecipitation"], 90)
+		setValue(period["windSpeed"], 55)
+		setValue(period["windGust"], 90)
+		period["shortForecast"] = "Thunderstorms"
+		period["detailedForecast"] = "Severe thunderstorms with heavy rain and damaging winds."
+	}
+}
+
+// Add to a quantitative value, choosing the amount by its unit
+func addToValue(quantity any, celsius, fahrenheit float64) {
+	q, ok := quantity.(map[string]any)
+	if !ok {
+		return
+	}
+
+	value, ok := q["value"].(float64)
+	if !ok {
+		return
+	}
+
+	if q["unitCode"] == "wmoUnit:degF" {
+		q["value"] = value + fahrenheit
+	} else {
+		q["value"] = value + celsius
+	}
+}
+
+// Set a quantitative value, leaving its unit alone
+func setValue(quantity any, value float64) {
+	if q, ok := quantity.(map[string]any); ok {
+		q["value"] = value
+		delete(q, "maxValue")
+		delete(q, "minValue")
+	}
+}
+
+// Copy decoded JSON so scenarios don't modify the fixtures
+func deepCopy(v map[string]any) map[string]any {
+	data, _ := json.Marshal(v)
+
+	var c map[string]any
+	json.Unmarshal(data, &c)
+	return c
+}
@@ -0,0 +1,149 @@
+package fakeapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	wapi "roofmail/weatherAPI"
+)
+
+var testNow = time.Date(2024, 7, 2, 15, 30, 0, 0, time.UTC)
+
+func startClient(t *testing.T, options Options, clientOpts ...wapi.ClientOption) wapi.WeatherAPI {
+	t.Helper()
+
+	options.FixturesDir = "../fixtures"
+	options.Now = func() time.Time { return testNow }
+	server, err := Start(options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	lat, lon := 40.0, -75.0
+	clientOpts = append(clientOpts, wapi.WithBaseURL(server.URL), wapi.WithRetryPolicy(wapi.RetryPolicy{MaxAttempts: 1}))
+	return wapi.NewWeatherGovAPI(server.Client(), &lat, &lon, clientOpts...)
+}
+
+func TestNormal(t *testing.T) {
+	api := startClient(t, Options{Scenario: Normal})
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	daily, err := api.GetDailyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(daily.Periods) != 14 {
+		t.Fatalf("expected 14 periods, got %d", len(daily.Periods))
+	}
+
+	// periods are moved to start at the current hour
+	first := daily.Periods[0]
+	if !first.StartTime.Equal(testNow.Truncate(time.Hour)) {
+		t.Errorf("expected first period to start at %v, got %v", testNow.Truncate(time.Hour), first.StartTime)
+	}
+	if first.EndTime.Sub(first.StartTime) != 8*time.Hour {
+		t.Errorf("expected period length to be kept, got %v", first.EndTime.Sub(first.StartTime))
+	}
+	if first.Temperature.Value != 30 {
+		t.Errorf("expected the recorded temperature, got %v", first.Temperature.Value)
+	}
+
+	hourly, err := api.GetHourlyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hourly.Periods) != 156 {
+		t.Errorf("expected 156 hourly periods, got %d", len(hourly.Periods))
+	}
+}
+
+func TestHeatWave(t *testing.T) {
+	api := startClient(t, Options{Scenario: HeatWave})
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	daily, err := api.GetDailyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := daily.Periods[0].Temperature.Value; got != 42 {
+		t.Errorf("expected 42C, got %v", got)
+	}
+	if daily.Periods[0].ShortForecast != "Hot" {
+		t.Errorf("unexpected short forecast: %q", daily.Periods[0].ShortForecast)
+	}
+}
+
+func TestStorm(t *testing.T) {
+	api := startClient(t, Options{Scenario: Storm})
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hourly, err := api.GetHourlyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	period := hourly.Periods[0]
+	if period.ProbabilityOfPrecipitation.Value != 90 || *period.WindSpeed.Value != 55 {
+		t.Errorf("expected stormy weather, got %+v", period)
+	}
+}
+
+func TestOutage(t *testing.T) {
+	api := startClient(t, Options{Scenario: Outage})
+	err := api.InitForecastAPI(context.Background(), nil, nil)
+	if !errors.Is(err, wapi.ErrUnexpectedServerError) {
+		t.Fatalf("expected ErrUnexpectedServerError, got %v", err)
+	}
+}
+
+func TestSlow(t *testing.T) {
+	api := startClient(t, Options{Scenario: Slow, Delay: time.Second}, wapi.WithTimeout(20*time.Millisecond))
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err == nil {
+		t.Fatal("expected the request to time out")
+	}
+}
+
+func TestAlerts(t *testing.T) {
+	tests := map[Scenario]int{Normal: 0, HeatWave: 1, Storm: 1}
+	for scenario, want := range tests {
+		server, err := Start(Options{Scenario: scenario, FixturesDir: "../fixtures"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		response, err := http.Get(server.URL + "/alerts/active?point=40,-75")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var alerts struct {
+			Features []any `json:"features"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&alerts)
+		response.Body.Close()
+		server.Close()
+
+		if err != nil || len(alerts.Features) != want {
+			t.Errorf("%s: expected %d alerts, got %d (%v)", scenario, want, len(alerts.Features), err)
+		}
+	}
+}
+
+func TestNewHandler_Errors(t *testing.T) {
+	if _, err := NewHandler(Options{Scenario: "blizzard", FixturesDir: "../fixtures"}); err == nil {
+		t.Error("expected error for unknown scenario")
+	}
+	if _, err := NewHandler(Options{FixturesDir: t.TempDir()}); err == nil {
+		t.Error("expected error for missing fixtures")
+	}
+}
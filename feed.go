@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"roofmail/client"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Most comfort window changes to keep
+const maxComfortChanges = 50
+
+// A comfort window the history follows from forecast to forecast. Its ID stays the same as later
+// forecasts move the window around, so calendar events and feed entries made from it do too.
+type trackedWindow struct {
+	ID     string               `json:"id"`
+	Window client.ComfortWindow `json:"window"`
+}
+
+// A comfort window that appeared or disappeared between forecasts
+type comfortChange struct {
+	Added      bool                 `json:"added"` // otherwise the window was cancelled
+	ID         string               `json:"id"`
+	Window     client.ComfortWindow `json:"window"`
+	DetectedAt time.Time            `json:"detectedAt"`
+}
+
+// comfortHistory remembers the comfort windows in the last forecast it saw, the ones later
+// forecasts dropped, and the changes between forecasts. It's saved to a file when it has one, so
+// window IDs and changes survive a restart; otherwise it's only kept in memory.
+type comfortHistory struct {
+	path string // where the history is saved, if anywhere
+
+	mu        sync.Mutex
+	recorded  bool
+	windows   []trackedWindow
+	cancelled []trackedWindow // dropped before they ended, until they would have ended
+	changes   []comfortChange
+}
+
+// Comfort window changes, for the feed and calendar. Set up in main.
+var windowHistory = &comfortHistory{}
+
+// The comfort window history as it's saved
+type savedComfortHistory struct {
+	Windows   []trackedWindow `json:"windows"`
+	Cancelled []trackedWindow `json:"cancelled"`
+	Changes   []comfortChange `json:"changes"`
+}
+
+// Load the comfort window history saved in dir, and save it there from now on. An empty dir keeps
+// the history in memory. A missing or unreadable history file starts a new history.
+func loadComfortHistory(dir string) (*comfortHistory, error) {
+	if dir == "" {
+		return &comfortHistory{}, nil
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &comfortHistory{path: filepath.Join(dir, "comfort-windows.json")}
+
+	data, err := os.ReadFile(history.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved savedComfortHistory
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		// the next recorded forecast overwrites it
+		infoLogger.Println("Ignoring unreadable comfort window history:", err)
+		return history, nil
+	}
+
+	history.recorded = true
+	history.windows = saved.Windows
+	history.cancelled = saved.Cancelled
+	history.changes = saved.Changes
+
+	return history, nil
+}
+
+// Save the history to its file, through a temporary file so a crash never leaves half of it. The
+// lock must be held.
+func (h *comfortHistory) save() error {
+	data, err := json.Marshal(savedComfortHistory{Windows: h.windows, Cancelled: h.cancelled, Changes: h.changes})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), "comfort-windows-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), h.path)
+}
+
+// Record the comfort windows in a forecast and return the changes so far, newest first.
+//
+// Each window is matched up with the window from the last forecast it overlaps most, since
+// forecasts shift windows around and trim the hours that have passed. A window without a match was
+// added, and a window left without one was cancelled, unless it's gone because it ended.
+func (h *comfortHistory) Record(windows []client.ComfortWindow, now time.Time) []comfortChange {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current := h.identify(upcomingWindows(windows, now))
+
+	// the first forecast is the baseline, there's nothing to compare it to
+	if h.recorded {
+		var changes []comfortChange
+		for _, window := range current {
+			if !containsWindow(h.windows, window.ID) {
+				changes = append(changes, comfortChange{Added: true, ID: window.ID, Window: window.Window, DetectedAt: now})
+			}
+		}
+		for _, window := range h.windows {
+			if window.Window.End.After(now) && !containsWindow(current, window.ID) {
+				changes = append(changes, comfortChange{Added: false, ID: window.ID, Window: window.Window, DetectedAt: now})
+				h.cancelled = append(h.cancelled, window)
+			}
+		}
+
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Window.Start.Before(changes[j].Window.Start)
+		})
+
+		h.changes = append(h.changes, changes...)
+		if len(h.changes) > maxComfortChanges {
+			h.changes = h.changes[len(h.changes)-maxComfortChanges:]
+		}
+	}
+
+	h.recorded = true
+	h.windows = current
+	h.cancelled = upcomingTracked(h.cancelled, now)
+
+	if h.path != "" {
+		if err := h.save(); err != nil {
+			infoLogger.Println("Error saving comfort window history:", err)
+		}
+	}
+
+	return h.newest()
+}
+
+// Identify the comfort windows in a forecast without recording it. Windows the history hasn't seen
+// get the IDs recording them would give them.
+func (h *comfortHistory) Identify(windows []client.ComfortWindow) []trackedWindow {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.identify(windows)
+}
+
+// Get the windows dropped from forecasts that haven't ended yet
+func (h *comfortHistory) Cancelled(now time.Time) []trackedWindow {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return upcomingTracked(h.cancelled, now)
+}
+
+// Give windows the IDs of the tracked windows they overlap most, each tracked window going to one
+// window at most. The others get new IDs from their start times. The lock must be held.
+func (h *comfortHistory) identify(windows []client.ComfortWindow) []trackedWindow {
+	type pair struct {
+		window, tracked int
+		overlap         time.Duration
+	}
+
+	var pairs []pair
+	for i, window := range windows {
+		for j, tracked := range h.windows {
+			if overlap := overlapOf(window, tracked.Window); overlap > 0 {
+				pairs = append(pairs, pair{window: i, tracked: j, overlap: overlap})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].overlap > pairs[j].overlap
+	})
+
+	identified := make([]trackedWindow, len(windows))
+	matched := make(map[int]bool)
+	for _, pair := range pairs {
+		if identified[pair.window].ID != "" || matched[pair.tracked] {
+			continue
+		}
+
+		identified[pair.window] = trackedWindow{ID: h.windows[pair.tracked].ID, Window: windows[pair.window]}
+		matched[pair.tracked] = true
+	}
+
+	// new IDs can't reuse one the history already has, which a window that moved off its start
+	// time could
+	for i, window := range windows {
+		if identified[i].ID != "" {
+			continue
+		}
+
+		id := window.Start.UTC().Format("20060102T1504Z")
+		for n := 2; containsWindow(identified, id) || containsWindow(h.windows, id) || containsWindow(h.cancelled, id); n++ {
+			id = fmt.Sprintf("%s-%d", window.Start.UTC().Format("20060102T1504Z"), n)
+		}
+
+		identified[i] = trackedWindow{ID: id, Window: window}
+	}
+
+	return identified
+}
+
+// Measure how long two windows overlap
+func overlapOf(a, b client.ComfortWindow) time.Duration {
+	start, end := a.Start, a.End
+	if b.Start.After(start) {
+		start = b.Start
+	}
+	if b.End.Before(end) {
+		end = b.End
+	}
+
+	return end.Sub(start)
+}
+
+// Determine whether a window with the ID is among the windows
+func containsWindow(windows []trackedWindow, id string) bool {
+	for _, window := range windows {
+		if window.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Leave out the windows that have ended
+func upcomingWindows(windows []client.ComfortWindow, now time.Time) []client.ComfortWindow {
+	var upcoming []client.ComfortWindow
+	for _, window := range windows {
+		if window.End.After(now) {
+			upcoming = append(upcoming, window)
+		}
+	}
+
+	return upcoming
+}
+
+// Leave out the tracked windows that have ended
+func upcomingTracked(windows []trackedWindow, now time.Time) []trackedWindow {
+	var upcoming []trackedWindow
+	for _, window := range windows {
+		if window.Window.End.After(now) {
+			upcoming = append(upcoming, window)
+		}
+	}
+
+	return upcoming
+}
+
+// Fetch the hourly forecast and record its comfort windows. This runs on the background refresh
+// interval, so the changes found don't depend on how often anyone reads the feed.
+func recordComfortWindows(ctx context.Context, now time.Time) {
+	// window times are the same in any units
+	hourly, err := forecasts.Hourly(ctx, wapi.US)
+	if err != nil {
+		infoLogger.Println("Error getting hourly forecast for comfort window history:", err)
+		return
+	}
+
+	// only compare fresh forecasts, a stale one hasn't changed
+	if hourly.Stale {
+		return
+	}
+
+	windowHistory.Record(comfortWindows(hourly.Forecast.Periods), now)
+}
+
+// Get the changes so far, newest first, without recording a forecast
+func (h *comfortHistory) Changes() []comfortChange {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.newest()
+}
+
+// List the changes newest first. The lock must be held.
+func (h *comfortHistory) newest() []comfortChange {
+	newest := make([]comfortChange, 0, len(h.changes))
+	for i := len(h.changes) - 1; i >= 0; i-- {
+		newest = append(newest, h.changes[i])
+	}
+
+	return newest
+}
+
+// atomFeed is an Atom (RFC 4287) feed
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Content atomText `xml:"content"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Build the feed, with an entry summarizing each day in the outlook followed by the comfort window
+// changes. baseURL is where the app is served from, and is used for links and entry IDs.
+func buildFeed(baseURL string, days []outlookDay, updated time.Time, changes []comfortChange) atomFeed {
+	feed := atomFeed{
+		Title:   "Roofmail",
+		ID:      baseURL + "/feed.atom",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Href: baseURL + "/feed.atom"},
+			{Rel: "alternate", Href: baseURL + "/week"},
+		},
+		Author: atomAuthor{Name: "Roofmail"},
+	}
+
+	for _, change := range changes {
+		title := "Comfort window cancelled: "
+		if change.Added {
+			title = "Comfort window added: "
+		}
+		title += describeWindow(change.Window.Start, change.Window.End)
+
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   title,
+			ID:      fmt.Sprintf("%s/feed.atom#change-%s-%t-%d", baseURL, change.ID, change.Added, change.DetectedAt.Unix()),
+			Updated: change.DetectedAt.UTC().Format(time.RFC3339),
+			Link:    atomLink{Rel: "alternate", Href: baseURL + "/hourly"},
+			Content: atomText{Type: "text", Text: title + "."},
+		})
+	}
+
+	for _, day := range days {
+		title := fmt.Sprintf("%s, %s: %s", day.Name, day.Date, day.ShortForecast)
+
+		summary := fmt.Sprintf("%s. High %s, low %s.", day.ShortForecast, day.High, day.Low)
+		switch {
+		case day.BestWindow != "":
+			summary += " Best time outside: " + day.BestWindow + "."
+		case day.HasHourly:
+			summary += " No comfortable stretch."
+		}
+
+		// the ID stays the same for the day, so readers update the entry as the forecast changes
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   title,
+			ID:      fmt.Sprintf("%s/feed.atom#day-%s", baseURL, day.ISODate),
+			Updated: updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{Rel: "alternate", Href: baseURL + "/week"},
+			Content: atomText{Type: "text", Text: summary},
+		})
+	}
+
+	return feed
+}
+
+// Get the URL the app was requested at, without a path
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
+
+// GET /feed.atom
+func feedHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// feed readers ignore cookies, so the units aren't remembered
+	system := readDisplayUnits(c)
+
+	daily, err := forecasts.Daily(ctx, apiUnits(system))
+	if err != nil {
+		infoLogger.Println("Error getting daily forecast for feed:", err)
+		c.String(http.StatusServiceUnavailable, forecastErrorMessage(err))
+		return
+	}
+
+	hourly, err := forecasts.Hourly(ctx, apiUnits(system))
+	if err != nil {
+		infoLogger.Println("Error getting hourly forecast for feed, leaving out comfort windows:", err)
+	}
+
+	// changes are recorded by the background refresh, reading the feed doesn't add any
+	days := buildOutlook(daily.Forecast.Periods, hourly.Forecast.Periods, system)
+	feed := buildFeed(requestBaseURL(c.Request), days, daily.FetchedAt, windowHistory.Changes())
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", append([]byte(xml.Header), out...))
+}
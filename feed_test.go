@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"roofmail/client"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A window on June 1st 2025 between the given hours, UTC
+func testWindow(start, end int) client.ComfortWindow {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	return client.ComfortWindow{Start: day.Add(time.Duration(start) * time.Hour), End: day.Add(time.Duration(end) * time.Hour)}
+}
+
+func TestComfortHistory(t *testing.T) {
+	history := &comfortHistory{}
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	if changes := history.Record([]client.ComfortWindow{testWindow(6, 10), testWindow(14, 18)}, now); len(changes) != 0 {
+		t.Fatalf("expected no changes from the first forecast, got %+v", changes)
+	}
+
+	// the morning window is trimmed and the afternoon one shifts, which aren't changes
+	now = now.Add(time.Hour)
+	if changes := history.Record([]client.ComfortWindow{testWindow(9, 10), testWindow(15, 19)}, now); len(changes) != 0 {
+		t.Errorf("expected no changes from shifted windows, got %+v", changes)
+	}
+
+	// the afternoon window is cancelled and an evening one added; the morning one just ended
+	now = now.Add(2 * time.Hour)
+	changes := history.Record([]client.ComfortWindow{testWindow(20, 22)}, now)
+	if len(changes) != 2 {
+		t.Fatalf("expected two changes, got %+v", changes)
+	}
+	if changes[0].Added != true || !changes[0].Window.Start.Equal(testWindow(20, 22).Start) {
+		t.Errorf("expected the evening window added last, got %+v", changes[0])
+	}
+	if changes[1].Added != false || !changes[1].Window.Start.Equal(testWindow(15, 19).Start) {
+		t.Errorf("expected the afternoon window cancelled, got %+v", changes[1])
+	}
+
+	if got := history.Changes(); len(got) != 2 {
+		t.Errorf("Changes() = %+v, want the same two changes", got)
+	}
+}
+
+func TestLoadComfortHistory(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	dir := t.TempDir()
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	history, err := loadComfortHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history.Record([]client.ComfortWindow{testWindow(10, 12), testWindow(14, 18)}, now)
+	history.Record([]client.ComfortWindow{testWindow(15, 19)}, now)
+	before := history.Identify([]client.ComfortWindow{testWindow(16, 19)})
+
+	// after a restart, windows keep their IDs and the changes are still there
+	restarted, err := loadComfortHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := restarted.Identify([]client.ComfortWindow{testWindow(16, 19)}); after[0].ID != before[0].ID {
+		t.Errorf("ID after a restart = %q, want %q", after[0].ID, before[0].ID)
+	}
+	if changes := restarted.Changes(); len(changes) != 1 || changes[0].Added || !changes[0].Window.Start.Equal(testWindow(10, 12).Start) {
+		t.Errorf("changes after a restart = %+v, want the morning window cancelled", changes)
+	}
+	if cancelled := restarted.Cancelled(now); len(cancelled) != 1 || cancelled[0].ID != history.Cancelled(now)[0].ID {
+		t.Errorf("cancelled after a restart = %+v, want %+v", cancelled, history.Cancelled(now))
+	}
+
+	// the first forecast after a restart is compared with the saved one
+	if changes := restarted.Record([]client.ComfortWindow{testWindow(15, 19), testWindow(20, 22)}, now); len(changes) != 2 || !changes[0].Added {
+		t.Errorf("expected the evening window added after a restart, got %+v", changes)
+	}
+
+	// an unreadable file starts over
+	if err := os.WriteFile(filepath.Join(dir, "comfort-windows.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	history, err = loadComfortHistory(dir)
+	if err != nil || len(history.Changes()) != 0 {
+		t.Errorf("loading an unreadable history = %+v, %v, want an empty history", history.Changes(), err)
+	}
+
+	// without a directory it's only kept in memory
+	history, err = loadComfortHistory("")
+	if err != nil || history.path != "" {
+		t.Errorf("loadComfortHistory(\"\") = %+v, %v, want a history in memory", history, err)
+	}
+}
+
+func TestRecordComfortWindows(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	windowHistory = &comfortHistory{}
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// comfortable until noon
+	api := &mockWeatherAPI{hourlyForecast: wapi.HourlyForecast{Periods: testHourlyPeriods(24, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23)}}
+	forecasts = newForecastStore(api)
+	recordComfortWindows(context.Background(), now)
+
+	// and now in the evening too
+	api.hourlyForecast = wapi.HourlyForecast{Periods: testHourlyPeriods(24, 12, 13, 14, 15, 16, 17)}
+	recordComfortWindows(context.Background(), now)
+	if changes := windowHistory.Changes(); len(changes) != 1 || !changes[0].Added || changes[0].Window.Start.Hour() != 18 {
+		t.Fatalf("expected the evening window added, got %+v", changes)
+	}
+
+	// a stale forecast isn't compared
+	api.forecastErr = errors.New("boom")
+	recordComfortWindows(context.Background(), now)
+	if changes := windowHistory.Changes(); len(changes) != 1 {
+		t.Errorf("expected no changes from a stale forecast, got %+v", changes)
+	}
+}
+
+func TestBuildFeed(t *testing.T) {
+	updated := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	days := []outlookDay{
+		{Name: "Sunday", Date: "Jun 1", ISODate: "2025-06-01", ShortForecast: "Sunny", High: "86°F", Low: "68°F", BestWindow: "Sun 2 PM – 6 PM", HasHourly: true},
+		{Name: "Monday", Date: "Jun 2", ISODate: "2025-06-02", ShortForecast: "Rain", High: "60°F", Low: missingValue},
+	}
+	changes := []comfortChange{{Added: false, Window: testWindow(14, 18), DetectedAt: updated}}
+
+	feed := buildFeed("http://localhost:8080", days, updated, changes)
+	if feed.ID != "http://localhost:8080/feed.atom" || feed.Updated != "2025-06-01T12:00:00Z" || len(feed.Entries) != 3 {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	if feed.Entries[0].Title != "Comfort window cancelled: Sun 2 PM – 6 PM" {
+		t.Errorf("unexpected change entry: %+v", feed.Entries[0])
+	}
+	if entry := feed.Entries[1]; entry.ID != "http://localhost:8080/feed.atom#day-2025-06-01" || entry.Content.Text != "Sunny. High 86°F, low 68°F. Best time outside: Sun 2 PM – 6 PM." {
+		t.Errorf("unexpected day entry: %+v", entry)
+	}
+	if entry := feed.Entries[2]; entry.Content.Text != "Rain. High 60°F, low —." {
+		t.Errorf("unexpected day entry: %+v", entry)
+	}
+}
+
+func TestFeedHandler(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+	gin.SetMode(gin.TestMode)
+
+	windowHistory = &comfortHistory{}
+	forecasts = newForecastStore(&mockWeatherAPI{
+		dailyForecast:  wapi.DailyForecast{Periods: testDailyPeriods(2)},
+		hourlyForecast: wapi.HourlyForecast{Periods: testHourlyPeriods(24)},
+	})
+
+	router := gin.New()
+	router.GET("/feed.atom", feedHandler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://roofmail.example/feed.atom?units=metric", nil))
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "application/atom+xml") {
+		t.Fatalf("status = %d, Content-Type %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if cookie := recorder.Header().Get("Set-Cookie"); cookie != "" {
+		t.Errorf("expected no units cookie, got %q", cookie)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("invalid feed: %v", err)
+	}
+	if len(feed.Entries) != 2 || !strings.HasPrefix(feed.Entries[0].ID, "http://roofmail.example/feed.atom#day-") {
+		t.Errorf("unexpected feed: %s", recorder.Body.String())
+	}
+
+	// reading the feed doesn't record anything, the background refresh does
+	if windowHistory.recorded {
+		t.Error("expected the feed handler to leave the history alone")
+	}
+}
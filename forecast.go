@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	wapi "roofmail/weatherAPI"
+)
+
+// forecastResult is a forecast along with how fresh it is. Hourly forecasts share the daily
+// forecast's shape, so both are held in Forecast.
+type forecastResult struct {
+	Forecast  wapi.DailyForecast
+	FetchedAt time.Time
+	// Stale is set when fetching failed and the last good forecast is being served instead. Err holds
+	// the failure. Notifications shouldn't be sent from stale data.
+	Stale bool
+	Err   error
+}
+
+// Describe how old the forecast is, e.g. "data is 12 minutes old"
+func (r forecastResult) age(now time.Time) string {
+	minutes := int(now.Sub(r.FetchedAt).Minutes())
+	if minutes == 1 {
+		return "data is 1 minute old"
+	}
+
+	return fmt.Sprintf("data is %d minutes old", minutes)
+}
+
+// forecastStore fetches forecasts and remembers the last good one for each kind and set of units,
+// so it can be served while weather.gov is unavailable.
+type forecastStore struct {
+	api wapi.WeatherAPI
+	now func() time.Time
+
+	mu   sync.RWMutex
+	last map[forecastKey]forecastResult
+}
+
+// Identifies a remembered forecast
+type forecastKey struct {
+	hourly bool
+	units  wapi.Units
+}
+
+func newForecastStore(api wapi.WeatherAPI) *forecastStore {
+	return &forecastStore{
+		api:  api,
+		now:  time.Now,
+		last: make(map[forecastKey]forecastResult),
+	}
+}
+
+// Get the daily forecast in the given units, falling back to the last good forecast on error. An
+// error is only returned when there's nothing to fall back to.
+func (s *forecastStore) Daily(ctx context.Context, apiUnits wapi.Units) (forecastResult, error) {
+	forecast, err := s.api.GetDailyForecast(ctx, wapi.WithUnits(apiUnits))
+	return s.remember(forecastKey{units: apiUnits}, forecast, err)
+}
+
+// Get the hourly forecast in the given units, with the same fallback as Daily.
+func (s *forecastStore) Hourly(ctx context.Context, apiUnits wapi.Units) (forecastResult, error) {
+	forecast, err := s.api.GetHourlyForecast(ctx, wapi.WithUnits(apiUnits))
+	return s.remember(forecastKey{hourly: true, units: apiUnits}, wapi.DailyForecast(forecast), err)
+}
+
+// Remember a good forecast, or fall back to the last one if fetching failed
+func (s *forecastStore) remember(key forecastKey, forecast wapi.DailyForecast, err error) (forecastResult, error) {
+	if err == nil {
+		result := forecastResult{Forecast: forecast, FetchedAt: s.now()}
+
+		s.mu.Lock()
+		s.last[key] = result
+		s.mu.Unlock()
+
+		return result, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	last, ok := s.last[key]
+	if !ok {
+		return forecastResult{}, err
+	}
+
+	last.Stale = true
+	last.Err = err
+	return last, nil
+}
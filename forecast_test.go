@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	wapi "roofmail/weatherAPI"
+)
+
+func TestForecastStore_ServesLastGoodForecast(t *testing.T) {
+	api := &mockWeatherAPI{dailyForecast: wapi.DailyForecast{Units: "us"}}
+	store := newForecastStore(api)
+
+	fetchedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return fetchedAt }
+
+	result, err := store.Daily(context.Background(), wapi.US)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Stale || result.Forecast.Units != "us" {
+		t.Errorf("expected fresh forecast, got %+v", result)
+	}
+
+	api.forecastErr = errors.New("received status code 503")
+	api.dailyForecast = wapi.DailyForecast{}
+	result, err = store.Daily(context.Background(), wapi.US)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Stale || result.Err == nil {
+		t.Errorf("expected stale result with error, got %+v", result)
+	}
+
+	// nothing was fetched in other units, so there's nothing to fall back to
+	if _, err := store.Daily(context.Background(), wapi.SI); err == nil {
+		t.Error("expected error with no forecast in SI units to fall back to")
+	}
+	if result.Forecast.Units != "us" || !result.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("expected the last good forecast, got %+v", result)
+	}
+	if got := result.age(fetchedAt.Add(12 * time.Minute)); got != "data is 12 minutes old" {
+		t.Errorf("age() = %q", got)
+	}
+}
+
+func TestForecastStore_ErrorWithoutFallback(t *testing.T) {
+	store := newForecastStore(&mockWeatherAPI{forecastErr: errors.New("boom")})
+	if _, err := store.Daily(context.Background(), wapi.US); err == nil {
+		t.Fatal("expected error with nothing to fall back to")
+	}
+}
+
+func TestForecastStore_HourlyIsSeparate(t *testing.T) {
+	api := &mockWeatherAPI{
+		dailyForecast:  wapi.DailyForecast{Units: "daily"},
+		hourlyForecast: wapi.HourlyForecast{Units: "hourly"},
+	}
+	store := newForecastStore(api)
+
+	if result, err := store.Hourly(context.Background(), wapi.US); err != nil || result.Forecast.Units != "hourly" {
+		t.Fatalf("Hourly() = %+v, %v", result, err)
+	}
+
+	// a cached hourly forecast isn't a fallback for the daily one
+	api.forecastErr = errors.New("boom")
+	if _, err := store.Daily(context.Background(), wapi.US); err == nil {
+		t.Error("expected error with no daily forecast to fall back to")
+	}
+	if result, err := store.Hourly(context.Background(), wapi.US); err != nil || !result.Stale {
+		t.Errorf("expected stale hourly forecast, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,245 @@
+package geocode
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GLOBALS
+var BASE_URL = "https://nominatim.openstreetmap.org"
+
+// ErrNotFound is returned when a query could not be resolved to a location.
+var ErrNotFound = errors.New("location not found")
+
+// Coordinates is a geographic point in decimal degrees
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Geocoder resolves a free-form location (an address or ZIP code) into coordinates.
+type Geocoder interface {
+	Geocode(ctx context.Context, query string) (Coordinates, error)
+}
+
+// nominatimGeocoder resolves locations against a Nominatim-compatible search API.
+type nominatimGeocoder struct {
+	client    *http.Client
+	baseURL   string
+	userAgent string
+}
+
+// NewNominatimGeocoder creates a Geocoder backed by a Nominatim-compatible API.
+//
+// An empty baseURL falls back to the public OpenStreetMap instance. Nominatim's usage policy
+// requires an identifying User-Agent, so one should always be provided.
+func NewNominatimGeocoder(client *http.Client, baseURL, userAgent string) Geocoder {
+	if baseURL == "" {
+		baseURL = BASE_URL
+	}
+
+	return &nominatimGeocoder{
+		client:    client,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		userAgent: userAgent,
+	}
+}
+
+// a single result from the Nominatim search endpoint
+type nominatimResult struct {
+	Latitude    string `json:"lat"`
+	Longitude   string `json:"lon"`
+	DisplayName string `json:"display_name"`
+}
+
+// Build the URL used for searches. ZIP codes are searched as US postal codes, since a free-form
+// search for five digits can match a postal code in another country.
+func buildSearchURL(baseURL, query string) string {
+	params := url.Values{}
+	if match := zipPattern.FindStringSubmatch(query); match != nil {
+		params.Add("postalcode", match[1])
+		params.Add("countrycodes", "us")
+	} else {
+		params.Add("q", query)
+	}
+	params.Add("format", "jsonv2")
+	params.Add("limit", "1")
+
+	return fmt.Sprintf("%s/search?%s", baseURL, params.Encode())
+}
+
+// Geocode looks up the query and returns the coordinates of the best match.
+func (g *nominatimGeocoder) Geocode(ctx context.Context, query string) (Coordinates, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return Coordinates{}, fmt.Errorf("empty location query")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, buildSearchURL(g.baseURL, query), nil)
+	if err != nil {
+		return Coordinates{}, err
+	}
+
+	if g.userAgent != "" {
+		request.Header.Set("User-Agent", g.userAgent)
+	}
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		return Coordinates{}, err
+	}
+
+	defer response.Body.Close()
+
+	// make sure the response is good
+	if response.StatusCode != http.StatusOK {
+		return Coordinates{}, fmt.Errorf("received status code %d", response.StatusCode)
+	}
+
+	var results []nominatimResult
+	err = json.NewDecoder(response.Body).Decode(&results)
+	if err != nil {
+		return Coordinates{}, err
+	}
+
+	if len(results) == 0 {
+		return Coordinates{}, fmt.Errorf("%w: %q", ErrNotFound, query)
+	}
+
+	return parseCoordinates(results[0].Latitude, results[0].Longitude)
+}
+
+// Parse a latitude/longitude pair of decimal strings
+func parseCoordinates(latitude, longitude string) (Coordinates, error) {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latitude), 64)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("invalid latitude %q: %w", latitude, err)
+	}
+
+	lon, err := strconv.ParseFloat(strings.TrimSpace(longitude), 64)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("invalid longitude %q: %w", longitude, err)
+	}
+
+	return Coordinates{Latitude: lat, Longitude: lon}, nil
+}
+
+// Table of US ZIP code centroids used for offline lookups. This covers a subset of ZIP codes and
+// can be extended by adding rows to zipcodes.csv.
+//
+//go:embed zipcodes.csv
+var zipCentroidsCSV string
+
+// matches "12345" and "12345-6789"
+var zipPattern = regexp.MustCompile(`^(\d{5})(?:-\d{4})?$`)
+
+// zipGeocoder resolves US ZIP codes from an embedded centroid table.
+type zipGeocoder struct {
+	once      sync.Once
+	centroids map[string]Coordinates
+	err       error
+}
+
+// NewZIPGeocoder creates a Geocoder that resolves US ZIP codes without network access.
+//
+// Queries that are not ZIP codes, or ZIP codes missing from the table, return ErrNotFound.
+func NewZIPGeocoder() Geocoder {
+	return &zipGeocoder{}
+}
+
+// Geocode looks up the centroid of a ZIP code.
+func (g *zipGeocoder) Geocode(ctx context.Context, query string) (Coordinates, error) {
+	g.once.Do(func() {
+		g.centroids, g.err = loadZIPCentroids(strings.NewReader(zipCentroidsCSV))
+	})
+	if g.err != nil {
+		return Coordinates{}, g.err
+	}
+
+	match := zipPattern.FindStringSubmatch(strings.TrimSpace(query))
+	if match == nil {
+		return Coordinates{}, fmt.Errorf("%w: %q is not a ZIP code", ErrNotFound, query)
+	}
+
+	coordinates, ok := g.centroids[match[1]]
+	if !ok {
+		return Coordinates{}, fmt.Errorf("%w: ZIP code %s", ErrNotFound, match[1])
+	}
+
+	return coordinates, nil
+}
+
+// Read a "zip,latitude,longitude" CSV table with a header row
+func loadZIPCentroids(r io.Reader) (map[string]Coordinates, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	centroids := make(map[string]Coordinates, len(records))
+	for i, record := range records {
+		// skip the header
+		if i == 0 {
+			continue
+		}
+
+		if len(record) != 3 {
+			return nil, fmt.Errorf("zip table line %d: expected 3 fields, got %d", i+1, len(record))
+		}
+
+		coordinates, err := parseCoordinates(record[1], record[2])
+		if err != nil {
+			return nil, fmt.Errorf("zip table line %d: %w", i+1, err)
+		}
+
+		centroids[record[0]] = coordinates
+	}
+
+	return centroids, nil
+}
+
+// chainGeocoder tries each geocoder in order until one succeeds.
+type chainGeocoder struct {
+	geocoders []Geocoder
+}
+
+// NewChainGeocoder creates a Geocoder that tries each of the given geocoders in order, returning
+// the first successful result. If all of them fail, the errors are joined together.
+func NewChainGeocoder(geocoders ...Geocoder) Geocoder {
+	return &chainGeocoder{geocoders: geocoders}
+}
+
+// Geocode returns the first successful lookup from the chain.
+func (g *chainGeocoder) Geocode(ctx context.Context, query string) (Coordinates, error) {
+	var errs []error
+	for _, geocoder := range g.geocoders {
+		coordinates, err := geocoder.Geocode(ctx, query)
+		if err == nil {
+			return coordinates, nil
+		}
+
+		// stop early if the caller gave up
+		if ctx.Err() != nil {
+			return Coordinates{}, ctx.Err()
+		}
+
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return Coordinates{}, fmt.Errorf("%w: no geocoders configured", ErrNotFound)
+	}
+
+	return Coordinates{}, errors.Join(errs...)
+}
@@ -0,0 +1,131 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuildSearchURL(t *testing.T) {
+	expected := "http://localhost/search?format=jsonv2&limit=1&q=350+5th+Ave"
+	got := buildSearchURL("http://localhost", "350 5th Ave")
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	// ZIP codes are only looked up in the US
+	for _, zip := range []string{"10001", "10001-1234"} {
+		expected = "http://localhost/search?countrycodes=us&format=jsonv2&limit=1&postalcode=10001"
+		got = buildSearchURL("http://localhost", zip)
+		if got != expected {
+			t.Errorf("expected %s for %s, got %s", expected, zip, got)
+		}
+	}
+}
+
+func TestNominatimGeocode_Success(t *testing.T) {
+	var gotQuery, gotAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("q")
+		gotAgent = r.Header.Get("User-Agent")
+		w.Write([]byte(`[{"lat":"40.7484","lon":"-73.9857","display_name":"Empire State Building"}]`))
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.Client(), server.URL+"/", "roofmail-test")
+	coordinates, err := geocoder.Geocode(context.Background(), "350 5th Ave, New York")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if coordinates.Latitude != 40.7484 || coordinates.Longitude != -73.9857 {
+		t.Errorf("unexpected coordinates: %+v", coordinates)
+	}
+	if gotQuery != "350 5th Ave, New York" {
+		t.Errorf("unexpected query: %q", gotQuery)
+	}
+	if gotAgent != "roofmail-test" {
+		t.Errorf("unexpected User-Agent: %q", gotAgent)
+	}
+}
+
+func TestNominatimGeocode_NoResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.Client(), server.URL, "")
+	_, err := geocoder.Geocode(context.Background(), "nowhere at all")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNominatimGeocode_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.Client(), server.URL, "")
+	_, err := geocoder.Geocode(context.Background(), "10001")
+	if err == nil {
+		t.Fatal("expected error for bad status code")
+	}
+}
+
+func TestZIPGeocode(t *testing.T) {
+	geocoder := NewZIPGeocoder()
+	tests := []struct {
+		query string
+		found bool
+	}{
+		{"10001", true},
+		{" 10001-1234 ", true},
+		{"00000", false},
+		{"New York", false},
+	}
+	for _, tt := range tests {
+		coordinates, err := geocoder.Geocode(context.Background(), tt.query)
+		if tt.found && err != nil {
+			t.Errorf("Geocode(%q) unexpected error: %v", tt.query, err)
+		}
+		if tt.found && (coordinates.Latitude < 40 || coordinates.Latitude > 41) {
+			t.Errorf("Geocode(%q) = %+v, want a point in New York", tt.query, coordinates)
+		}
+		if !tt.found && !errors.Is(err, ErrNotFound) {
+			t.Errorf("Geocode(%q) expected ErrNotFound, got %v", tt.query, err)
+		}
+	}
+}
+
+func TestLoadZIPCentroids_Invalid(t *testing.T) {
+	_, err := loadZIPCentroids(strings.NewReader("zip,latitude,longitude\n10001,north,-73.99\n"))
+	if err == nil {
+		t.Error("expected error for invalid latitude")
+	}
+}
+
+func TestChainGeocode_FallsBack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	geocoder := NewChainGeocoder(NewNominatimGeocoder(server.Client(), server.URL, ""), NewZIPGeocoder())
+	coordinates, err := geocoder.Geocode(context.Background(), "94105")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if coordinates.Longitude > -122 {
+		t.Errorf("unexpected coordinates: %+v", coordinates)
+	}
+
+	_, err = geocoder.Geocode(context.Background(), "not a zip")
+	if err == nil {
+		t.Fatal("expected error when every geocoder fails")
+	}
+}
@@ -0,0 +1,225 @@
+package httpfixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Modes for TransportFromEnv
+const (
+	ModeOff    = ""
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// ErrNoFixture is returned when replaying a request that was never recorded.
+var ErrNoFixture = errors.New("no fixture recorded")
+
+// Fixture is a recorded HTTP exchange, stored as one JSON file.
+type Fixture struct {
+	Request struct {
+		Method       string `json:"method"`
+		URL          string `json:"url"`
+		FeatureFlags string `json:"featureFlags,omitempty"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int         `json:"statusCode"`
+		Header     http.Header `json:"header,omitempty"`
+		// Body is kept as raw JSON when the response is JSON, so fixtures stay readable and editable
+		Body     json.RawMessage `json:"body,omitempty"`
+		BodyText string          `json:"bodyText,omitempty"`
+	} `json:"response"`
+}
+
+// Headers that identify a recording session rather than the response
+var skippedHeaders = []string{"Date", "Set-Cookie", "X-Correlation-Id", "X-Request-Id", "X-Server-Id", "Server-Timing"}
+
+// matches runs of characters that don't belong in file names
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.,-]+`)
+
+// Build the file name for a request. The name is readable, with a hash of everything that
+// distinguishes the request so that different queries and feature flags don't collide.
+func FileName(request *http.Request) string {
+	key := request.Method + " " + request.URL.String() + " " + request.Header.Get("Feature-Flags")
+	sum := sha256.Sum256([]byte(key))
+
+	name := unsafeChars.ReplaceAllString(request.URL.Host+request.URL.Path, "_")
+	name = strings.Trim(name, "_")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+
+	return fmt.Sprintf("%s_%s_%s.json", request.Method, name, hex.EncodeToString(sum[:4]))
+}
+
+// recorder is a RoundTripper that saves every exchange it forwards.
+type recorder struct {
+	transport http.RoundTripper
+	dir       string
+}
+
+// NewRecorder creates a RoundTripper that forwards requests to `transport` and writes each
+// exchange to `dir`, replacing any earlier recording of the same request.
+func NewRecorder(transport http.RoundTripper, dir string) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &recorder{transport: transport, dir: dir}
+}
+
+func (r *recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := r.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	var fixture Fixture
+	fixture.Request.Method = request.Method
+	fixture.Request.URL = request.URL.String()
+	fixture.Request.FeatureFlags = request.Header.Get("Feature-Flags")
+	fixture.Response.StatusCode = response.StatusCode
+	fixture.Response.Header = response.Header.Clone()
+	for _, header := range skippedHeaders {
+		fixture.Response.Header.Del(header)
+	}
+
+	if json.Valid(body) {
+		fixture.Response.Body = body
+	} else {
+		fixture.Response.BodyText = string(body)
+	}
+
+	err = Save(filepath.Join(r.dir, FileName(request)), &fixture)
+	if err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+
+	return response, nil
+}
+
+// Save writes a fixture as indented JSON, creating the directory if needed.
+func Save(path string, fixture *Fixture) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(fixture)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Load reads a fixture file.
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	err = json.Unmarshal(data, &fixture)
+	if err != nil {
+		return nil, fmt.Errorf("reading fixture %s: %w", path, err)
+	}
+
+	return &fixture, nil
+}
+
+// Build the HTTP response for a fixture
+func (f *Fixture) response(request *http.Request) *http.Response {
+	body := []byte(f.Response.BodyText)
+	if len(f.Response.Body) > 0 {
+		body = f.Response.Body
+	}
+
+	header := f.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
+
+// replayer is a RoundTripper that serves recorded exchanges and never touches the network.
+type replayer struct {
+	dir string
+}
+
+// NewReplayer creates a RoundTripper that answers requests from the fixtures in `dir`. Requests
+// without a recording fail with ErrNoFixture.
+func NewReplayer(dir string) http.RoundTripper {
+	return &replayer{dir: dir}
+}
+
+func (r *replayer) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+
+	path := filepath.Join(r.dir, FileName(request))
+	fixture, err := Load(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w for %s %s (expected %s)", ErrNoFixture, request.Method, request.URL, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return fixture.response(request), nil
+}
+
+// TransportFromEnv wraps `transport` according to the HTTP_FIXTURES environment variable:
+// "record" saves exchanges, "replay" serves them offline, and an empty value (ModeOff) leaves the
+// transport untouched. Any other value is an error. Fixtures live in FIXTURES_DIR, or ./fixtures
+// when that isn't set.
+func TransportFromEnv(transport http.RoundTripper) (http.RoundTripper, error) {
+	dir := os.Getenv("FIXTURES_DIR")
+	if dir == "" {
+		dir = "fixtures"
+	}
+
+	switch mode := os.Getenv("HTTP_FIXTURES"); mode {
+	case ModeOff:
+		return transport, nil
+	case ModeRecord:
+		return NewRecorder(transport, dir), nil
+	case ModeReplay:
+		return NewReplayer(dir), nil
+	default:
+		return nil, fmt.Errorf("unknown HTTP_FIXTURES mode %q, expected record or replay", mode)
+	}
+}
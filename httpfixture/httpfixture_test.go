@@ -0,0 +1,125 @@
+package httpfixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Header().Set("X-Correlation-Id", "abc123")
+		if r.URL.Path == "/text" {
+			w.Write([]byte("plain text"))
+			return
+		}
+		w.Write([]byte(`{"units":"` + r.URL.Query().Get("units") + `"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recording := &http.Client{Transport: NewRecorder(server.Client().Transport, dir)}
+	for _, path := range []string{"/forecast?units=us", "/forecast?units=si", "/text"} {
+		response, err := recording.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 fixtures, got %d", len(entries))
+	}
+
+	server.Close()
+	replaying := &http.Client{Transport: NewReplayer(dir)}
+	tests := map[string]string{
+		"/forecast?units=si": `{"units":"si"}`,
+		"/forecast?units=us": `{"units":"us"}`,
+		"/text":              "plain text",
+	}
+	for path, want := range tests {
+		response, err := replaying.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		// JSON bodies are stored indented
+		var compact bytes.Buffer
+		if json.Compact(&compact, body) == nil {
+			body = compact.Bytes()
+		}
+
+		if string(body) != want {
+			t.Errorf("replay %s = %q, want %q", path, body, want)
+		}
+		if response.Header.Get("Content-Type") != "application/geo+json" || response.Header.Get("X-Correlation-Id") != "" {
+			t.Errorf("unexpected replayed headers: %v", response.Header)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected replay not to hit the server, got %d calls", calls)
+	}
+}
+
+func TestReplay_Missing(t *testing.T) {
+	client := &http.Client{Transport: NewReplayer(t.TempDir())}
+	_, err := client.Get("https://api.weather.gov/points/0,0")
+	if !errors.Is(err, ErrNoFixture) {
+		t.Fatalf("expected ErrNoFixture, got %v", err)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "https://api.weather.gov/gridpoints/PHI/50,75/forecast?units=us", nil)
+	plain := FileName(request)
+	if matched, _ := filepath.Match("GET_api.weather.gov_gridpoints_PHI_50,75_forecast_*.json", plain); !matched {
+		t.Errorf("unexpected file name: %s", plain)
+	}
+
+	request.Header.Set("Feature-Flags", "forecast_temperature_qv")
+	if FileName(request) == plain {
+		t.Error("expected feature flags to change the file name")
+	}
+}
+
+func TestTransportFromEnv(t *testing.T) {
+	t.Setenv("FIXTURES_DIR", t.TempDir())
+
+	t.Setenv("HTTP_FIXTURES", ModeOff)
+	if transport, err := TransportFromEnv(http.DefaultTransport); err != nil || transport != http.DefaultTransport {
+		t.Errorf("TransportFromEnv(off) = %T, %v, want the given transport", transport, err)
+	}
+
+	t.Setenv("HTTP_FIXTURES", ModeRecord)
+	if transport, err := TransportFromEnv(http.DefaultTransport); err != nil {
+		t.Errorf("TransportFromEnv(record) unexpected error: %v", err)
+	} else if _, ok := transport.(*recorder); !ok {
+		t.Errorf("TransportFromEnv(record) = %T, want recorder", transport)
+	}
+
+	t.Setenv("HTTP_FIXTURES", ModeReplay)
+	if transport, err := TransportFromEnv(http.DefaultTransport); err != nil {
+		t.Errorf("TransportFromEnv(replay) unexpected error: %v", err)
+	} else if _, ok := transport.(*replayer); !ok {
+		t.Errorf("TransportFromEnv(replay) = %T, want replayer", transport)
+	}
+
+	t.Setenv("HTTP_FIXTURES", "rewind")
+	if _, err := TransportFromEnv(nil); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"roofmail/units"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Default time between background forecast refreshes
+const defaultRefreshInterval = 10 * time.Minute
+
+// How often to send a comment to keep idle event streams open through proxies
+const keepAliveInterval = 30 * time.Second
+
+var errEmptyForecast = errors.New("forecast has no periods")
+
+// pageUpdate is what the index page shows for a forecast. It's rendered into the page, and sent to
+// open pages as the forecast is refreshed.
+type pageUpdate struct {
+	Heading     string `json:"heading"`
+	Message     string `json:"message"`
+	Comfort     string `json:"comfort"`
+	RefreshDate string `json:"refreshDate"`
+	Stale       bool   `json:"stale"`
+	Units       string `json:"units"`
+}
+
+// Build what the index page shows for a daily forecast
+func buildPageUpdate(result forecastResult, system units.System, now time.Time) (pageUpdate, error) {
+	if len(result.Forecast.Periods) == 0 {
+		return pageUpdate{}, errEmptyForecast
+	}
+
+	period := result.Forecast.Periods[0]
+
+	// values in units we can't read are left out of the conditions, so this isn't fatal
+	current, err := period.Conditions()
+	if err != nil {
+		infoLogger.Println("Error reading forecast conditions:", err)
+	}
+
+	return pageUpdate{
+		Heading:     shortForecast(period),
+		Message:     comfortMessage(current, system),
+		Comfort:     assessComfort(current).String(),
+		RefreshDate: refreshDate(result, now),
+		Stale:       result.Stale,
+		Units:       system.String(),
+	}, nil
+}
+
+// Identifies the forecast a page update was built from, so open pages aren't sent the same
+// forecast again with only a new refresh time. A stale forecast's age is part of it, so the age
+// shown on open pages keeps up while weather.gov is unavailable.
+type forecastVersion struct {
+	periods  string // fingerprint of the periods
+	stale    bool
+	staleAge int // minutes since a stale forecast was fetched
+}
+
+// Get the version of a forecast at a time. The periods are compared rather than the forecast's
+// update time, since Open-Meteo doesn't report one and stamps every fetch instead.
+func versionOf(result forecastResult, now time.Time) forecastVersion {
+	// periods always marshal, they're plain data
+	periods, _ := json.Marshal(result.Forecast.Periods)
+	sum := sha256.Sum256(periods)
+
+	version := forecastVersion{periods: hex.EncodeToString(sum[:]), stale: result.Stale}
+	if result.Stale {
+		// the same minutes the page shows
+		version.staleAge = int(now.Sub(result.FetchedAt).Minutes())
+	}
+
+	return version
+}
+
+// liveUpdates fans page updates out to the open event streams, each of which wants updates in
+// one unit system.
+type liveUpdates struct {
+	mu          sync.Mutex
+	subscribers map[chan pageUpdate]units.System
+	versions    map[units.System]forecastVersion // last forecast sent in each system
+}
+
+func newLiveUpdates() *liveUpdates {
+	return &liveUpdates{
+		subscribers: make(map[chan pageUpdate]units.System),
+		versions:    make(map[units.System]forecastVersion),
+	}
+}
+
+// Page updates for open event streams
+var live = newLiveUpdates()
+
+// Subscribe to updates in a unit system. The returned function unsubscribes.
+func (l *liveUpdates) Subscribe(system units.System) (<-chan pageUpdate, func()) {
+	// buffer one update so a slow stream only misses ones that are already out of date
+	updates := make(chan pageUpdate, 1)
+
+	l.mu.Lock()
+	l.subscribers[updates] = system
+	l.mu.Unlock()
+
+	return updates, func() {
+		l.mu.Lock()
+		delete(l.subscribers, updates)
+		l.mu.Unlock()
+	}
+}
+
+// List the unit systems with subscribers
+func (l *liveUpdates) Systems() []units.System {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seen := make(map[units.System]bool)
+	var systems []units.System
+	for _, system := range l.subscribers {
+		if !seen[system] {
+			seen[system] = true
+			systems = append(systems, system)
+		}
+	}
+
+	return systems
+}
+
+// Send an update to the subscribers that want its unit system. Subscribers that haven't taken the
+// last update yet get this one instead.
+func (l *liveUpdates) Publish(system units.System, update pageUpdate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for updates, wanted := range l.subscribers {
+		if wanted != system {
+			continue
+		}
+
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- update
+	}
+}
+
+// Remember the forecast version about to be sent in a unit system, reporting whether it's
+// different from the last one
+func (l *liveUpdates) changed(system units.System, version forecastVersion) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if last, ok := l.versions[system]; ok && last == version {
+		return false
+	}
+
+	l.versions[system] = version
+	return true
+}
+
+// Fetch the daily forecast in each unit system open pages are using, and send them the update when
+// the forecast changed, went stale or fresh again, or is stale and a minute older
+func refreshLivePages(ctx context.Context, now time.Time) {
+	for _, system := range live.Systems() {
+		result, err := forecasts.Daily(ctx, apiUnits(system))
+		if err != nil {
+			infoLogger.Println("Error refreshing daily forecast:", err)
+			continue
+		}
+
+		if !live.changed(system, versionOf(result, now)) {
+			continue
+		}
+
+		update, err := buildPageUpdate(result, system, now)
+		if err != nil {
+			infoLogger.Println("Error reading refreshed daily forecast:", err)
+			continue
+		}
+
+		live.Publish(system, update)
+	}
+}
+
+// Refresh the forecasts now and then every interval until the context is done, recording comfort
+// window changes and updating open pages. Daily forecasts are only fetched while pages are open.
+func runBackgroundRefresh(ctx context.Context, interval time.Duration) {
+	refresh := func() {
+		refreshCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		recordComfortWindows(refreshCtx, now)
+		refreshLivePages(refreshCtx, now)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	refresh()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// GET /events streams page updates as Server-Sent Events named "forecast"
+func eventsHandler(c *gin.Context) {
+	updates, unsubscribe := live.Subscribe(requestDisplayUnits(c))
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	// send the headers now, so the browser knows the stream is open
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case update := <-updates:
+			c.SSEvent("forecast", update)
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		}
+
+		return true
+	})
+}
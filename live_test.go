@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"roofmail/units"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuildPageUpdate(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	if _, err := buildPageUpdate(forecastResult{}, units.Imperial, time.Now()); !errors.Is(err, errEmptyForecast) {
+		t.Errorf("expected errEmptyForecast, got %v", err)
+	}
+
+	result := forecastResult{Forecast: wapi.DailyForecast{Periods: []wapi.Period{testPeriod(12, 30, floatPtr(0))}}}
+	update, err := buildPageUpdate(result, units.Metric, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.Heading != "Sunny" || update.Comfort != "comfortable" || update.Units != "metric" || update.RefreshDate != "2025-06-01T12:00:00Z" {
+		t.Errorf("unexpected update: %+v", update)
+	}
+	if !strings.Contains(update.Message, "30°C") {
+		t.Errorf("expected a metric message, got %q", update.Message)
+	}
+}
+
+func TestLiveUpdates(t *testing.T) {
+	updates := newLiveUpdates()
+
+	metric, unsubscribeMetric := updates.Subscribe(units.Metric)
+	imperial, unsubscribeImperial := updates.Subscribe(units.Imperial)
+	if got := updates.Systems(); len(got) != 2 {
+		t.Errorf("Systems() = %v, want both", got)
+	}
+
+	// a subscriber that falls behind only gets the latest update
+	updates.Publish(units.Metric, pageUpdate{Heading: "first"})
+	updates.Publish(units.Metric, pageUpdate{Heading: "second"})
+	if got := <-metric; got.Heading != "second" {
+		t.Errorf("got %q, want the latest update", got.Heading)
+	}
+	select {
+	case got := <-imperial:
+		t.Errorf("imperial subscriber got a metric update: %+v", got)
+	default:
+	}
+
+	unsubscribeMetric()
+	unsubscribeImperial()
+	if got := updates.Systems(); len(got) != 0 {
+		t.Errorf("Systems() = %v, want none after unsubscribing", got)
+	}
+}
+
+func TestRefreshLivePages(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	api := &mockWeatherAPI{dailyForecast: wapi.DailyForecast{Periods: []wapi.Period{testPeriod(12, 30, floatPtr(0))}}}
+	forecasts = newForecastStore(api)
+	forecasts.now = func() time.Time { return now }
+	live = newLiveUpdates()
+
+	updates, unsubscribe := live.Subscribe(units.Imperial)
+	defer unsubscribe()
+
+	refreshLivePages(context.Background(), now)
+	select {
+	case update := <-updates:
+		if update.Heading != "Sunny" || update.Units != "imperial" {
+			t.Errorf("unexpected update: %+v", update)
+		}
+	default:
+		t.Fatal("expected an update")
+	}
+
+	// take the next update, if one was sent
+	next := func() (pageUpdate, bool) {
+		select {
+		case update := <-updates:
+			return update, true
+		default:
+			return pageUpdate{}, false
+		}
+	}
+
+	// the same forecast isn't sent again, even refreshed later
+	now = now.Add(10 * time.Minute)
+	refreshLivePages(context.Background(), now)
+	if update, ok := next(); ok {
+		t.Errorf("unexpected update for an unchanged forecast: %+v", update)
+	}
+
+	api.dailyForecast = wapi.DailyForecast{Periods: []wapi.Period{testPeriod(12, 5, floatPtr(0))}}
+	refreshLivePages(context.Background(), now)
+	if update, ok := next(); !ok || update.Comfort != "uncomfortable" {
+		t.Errorf("expected an update for the changed forecast, got %+v", update)
+	}
+
+	// the last forecast going stale is sent, and sent again as it gets older
+	api.forecastErr = errors.New("boom")
+	refreshLivePages(context.Background(), now)
+	if update, ok := next(); !ok || !update.Stale || !strings.Contains(update.RefreshDate, "data is 0 minutes old") {
+		t.Errorf("expected a stale update, got %+v", update)
+	}
+	refreshLivePages(context.Background(), now.Add(30*time.Second))
+	if update, ok := next(); ok {
+		t.Errorf("unexpected stale update within the same minute: %+v", update)
+	}
+	refreshLivePages(context.Background(), now.Add(10*time.Minute))
+	if update, ok := next(); !ok || !strings.Contains(update.RefreshDate, "data is 10 minutes old") {
+		t.Errorf("expected a stale update with the new age, got %+v", update)
+	}
+
+	// nothing is sent when there's no forecast to fall back to
+	forecasts = newForecastStore(api)
+	live = newLiveUpdates()
+	updates, unsubscribe = live.Subscribe(units.Imperial)
+	defer unsubscribe()
+	refreshLivePages(context.Background(), now)
+	if update, ok := next(); ok {
+		t.Errorf("unexpected update after an error: %+v", update)
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+	gin.SetMode(gin.TestMode)
+
+	live = newLiveUpdates()
+
+	router := gin.New()
+	router.GET("/events", eventsHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?units=metric", nil)
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+
+	if got := response.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	// the headers are sent after subscribing, so the stream is listening by now
+	live.Publish(units.Metric, pageUpdate{Heading: "Hot", RefreshDate: "now"})
+
+	scanner := bufio.NewScanner(response.Body)
+	var lines []string
+	for scanner.Scan() && scanner.Text() != "" {
+		lines = append(lines, scanner.Text())
+	}
+
+	event := strings.Join(lines, "\n")
+	if !strings.Contains(event, "event:forecast") || !strings.Contains(event, `"heading":"Hot"`) {
+		t.Errorf("unexpected event: %q", event)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"roofmail/client"
+	"roofmail/units"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How many days the outlook shows
+const outlookDays = 7
+
+// OutlookData is the data for the seven-day outlook page
+type OutlookData struct {
+	Title       string
+	Page        string
+	Heading     string
+	Days        []outlookDay
+	RefreshDate string
+	Stale       bool
+	Units       string
+}
+
+// A day in the outlook, from its daytime and overnight periods
+type outlookDay struct {
+	Name          string
+	Date          string
+	ISODate       string
+	ShortForecast string
+	Icon          string
+	High          string
+	Low           string
+	Comfort       string // of the daytime period, or the overnight one if the day is nearly over
+	BestWindow    string // the longest comfortable window starting that day, if any
+	HasHourly     bool   // whether the hourly forecast reaches this day
+}
+
+// Build the outlook from the daily periods, finding each day's best comfort window in the hourly
+// periods. The hourly forecast doesn't cover the whole week, so later days have no windows.
+func buildOutlook(daily, hourly []wapi.Period, system units.System) []outlookDay {
+	var days []outlookDay
+	var dates []string
+	for _, period := range daily {
+		date := period.StartTime.Format(time.DateOnly)
+		if len(dates) == 0 || dates[len(dates)-1] != date {
+			if len(days) == outlookDays {
+				break
+			}
+
+			days = append(days, outlookDay{
+				Name:    period.StartTime.Format("Monday"),
+				Date:    period.StartTime.Format("Jan 2"),
+				ISODate: date,
+				High:    missingValue,
+				Low:     missingValue,
+			})
+			dates = append(dates, date)
+		}
+
+		day := &days[len(days)-1]
+
+		conditions, err := period.Conditions()
+		if err != nil {
+			debugLogger.Printf("Reading conditions for period %d: %v", period.Number, err)
+		}
+
+		// weather.gov gives the high for the daytime period and the low overnight
+		if temperature, ok := conditions.Temperature.Get(); ok {
+			if period.IsDaytime {
+				day.High = temperature.Format(system)
+			} else {
+				day.Low = temperature.Format(system)
+			}
+		}
+
+		// describe the day by its daytime period when there is one
+		if period.IsDaytime || day.ShortForecast == "" {
+			day.ShortForecast = period.ShortForecast
+			day.Icon = period.Icon
+			day.Comfort = assessComfort(conditions).String()
+		}
+	}
+
+	best := make(map[string]client.ComfortWindow)
+	for _, window := range comfortWindows(hourly) {
+		date := window.Start.Format(time.DateOnly)
+		if current, ok := best[date]; !ok || window.End.Sub(window.Start) > current.End.Sub(current.Start) {
+			best[date] = window
+		}
+	}
+
+	covered := make(map[string]bool)
+	for _, period := range hourly {
+		covered[period.StartTime.Format(time.DateOnly)] = true
+	}
+
+	for i, date := range dates {
+		days[i].HasHourly = covered[date]
+		if window, ok := best[date]; ok {
+			days[i].BestWindow = describeWindow(window.Start, window.End)
+		}
+	}
+
+	return days
+}
+
+func outlookHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	system := requestDisplayUnits(c)
+
+	daily, err := forecasts.Daily(ctx, apiUnits(system))
+	if err != nil {
+		infoLogger.Println("Error getting daily forecast:", err)
+		c.String(http.StatusInternalServerError, forecastErrorMessage(err))
+		return
+	}
+
+	// the outlook is still useful without comfort windows
+	hourly, err := forecasts.Hourly(ctx, apiUnits(system))
+	if err != nil {
+		infoLogger.Println("Error getting hourly forecast, showing the outlook without comfort windows:", err)
+	}
+
+	if daily.Stale {
+		infoLogger.Println("Serving stale forecast, error getting daily forecast:", daily.Err)
+	}
+
+	data := OutlookData{
+		Title:       "Roofmail — This week",
+		Page:        "week",
+		Heading:     "This week",
+		Days:        buildOutlook(daily.Forecast.Periods, hourly.Forecast.Periods, system),
+		RefreshDate: refreshDate(daily, time.Now()),
+		Stale:       daily.Stale,
+		Units:       system.String(),
+	}
+
+	pages.Render(c, "week", data)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"roofmail/units"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Daily periods alternating day and night, starting with the daytime period on June 1st 2025
+func testDailyPeriods(days int) []wapi.Period {
+	var periods []wapi.Period
+	for day := 0; day < days; day++ {
+		start := time.Date(2025, 6, 1+day, 6, 0, 0, 0, time.UTC)
+		periods = append(periods,
+			wapi.Period{
+				Number: 2*day + 1, StartTime: start, EndTime: start.Add(12 * time.Hour), IsDaytime: true,
+				ShortForecast: "Sunny", Icon: "https://example.com/day.png",
+				Temperature: &wapi.UnitValue{Value: 30, UnitCode: "wmoUnit:degC"},
+			},
+			wapi.Period{
+				Number: 2*day + 2, StartTime: start.Add(12 * time.Hour), EndTime: start.Add(24 * time.Hour),
+				ShortForecast: "Clear", Icon: "https://example.com/night.png",
+				Temperature: &wapi.UnitValue{Value: 20, UnitCode: "wmoUnit:degC"},
+			},
+		)
+	}
+	return periods
+}
+
+func TestBuildOutlook(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	// the forecast starts overnight, so the first day only has its night
+	daily := testDailyPeriods(8)[1:]
+	daily[2].Temperature = nil
+
+	// hourly data covers the first two days, with a short window on the 1st and two on the 2nd
+	hourly := testHourlyPeriods(48, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 28, 29)
+
+	days := buildOutlook(daily, hourly, units.Imperial)
+	if len(days) != outlookDays {
+		t.Fatalf("got %d days, want %d", len(days), outlookDays)
+	}
+
+	first := days[0]
+	if first.Name != "Sunday" || first.ShortForecast != "Clear" || first.High != missingValue || first.Low != "68°F" {
+		t.Errorf("unexpected first day: %+v", first)
+	}
+	if first.BestWindow != "Sun 12 AM – 2 PM" || !first.HasHourly {
+		t.Errorf("unexpected first day window: %+v", first)
+	}
+
+	second := days[1]
+	if second.ShortForecast != "Sunny" || second.Icon != "https://example.com/day.png" || second.High != "86°F" || second.Low != missingValue {
+		t.Errorf("unexpected second day: %+v", second)
+	}
+	if second.BestWindow != "Mon 6 AM – Tue 12 AM" {
+		t.Errorf("expected the longer window on the 2nd, got %q", second.BestWindow)
+	}
+
+	if last := days[6]; last.Date != "Jun 7" || last.HasHourly || last.BestWindow != "" {
+		t.Errorf("unexpected last day: %+v", last)
+	}
+}
+
+func TestOutlookHandler(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+	gin.SetMode(gin.TestMode)
+	pages = testTemplates(t)
+
+	// the hourly forecast comes from the same mock, so failing both shows the error page
+	forecasts = newForecastStore(&mockWeatherAPI{forecastErr: errors.New("boom")})
+
+	router := gin.New()
+	router.GET("/week", outlookHandler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/week", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", recorder.Code)
+	}
+
+	forecasts = newForecastStore(&mockWeatherAPI{dailyForecast: wapi.DailyForecast{Periods: testDailyPeriods(7)}})
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/week", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", recorder.Code, recorder.Body.String())
+	}
+	body := recorder.Body.String()
+	if strings.Count(body, `class="outlook-day`) != 7 || !strings.Contains(body, "Saturday") || !strings.Contains(body, "86°F") {
+		t.Errorf("expected seven days, got %s", body)
+	}
+}
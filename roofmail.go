@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"roofmail/fakeapi"
+	"roofmail/geocode"
+	"roofmail/httpfixture"
+	"roofmail/units"
 	wapi "roofmail/weatherAPI"
 
 	"github.com/gin-gonic/gin"
@@ -19,7 +26,9 @@ import (
 
 // Config holds the configuration for the application
 type Config struct {
-	Version string
+	Version         string
+	DisplayUnits    units.System
+	RefreshInterval time.Duration
 }
 
 // Log instances
@@ -37,9 +46,33 @@ var (
 // Weather API
 var w wapi.WeatherAPI
 
+// Units to show quantities in
+var displayUnits units.System
+
+// Daily forecasts, with the last good one kept for outages
+var forecasts *forecastStore
+
+// Limits requests to weather.gov across every client
+var weatherGovLimiter = wapi.NewRateLimiter(2, 5)
+
 func main() {
-	err := godotenv.Load()
+	// serve a fake weather.gov API instead of the app
+	if len(os.Args) > 1 && os.Args[1] == "fakeapi" {
+		err := runFakeAPI(os.Args[2:])
+		if err != nil {
+			log.Fatalln("Error running fake API:", err)
+		}
+		return
+	}
+
+	flags, err := parseServeFlags(os.Args[1:])
 	if err != nil {
+		log.Fatalln("Error parsing flags:", err)
+	}
+
+	// settings can come from the environment instead, e.g. in a container
+	err = godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		fmt.Println("Error loading .env file")
 		return
 	}
@@ -48,46 +81,114 @@ func main() {
 	initLogs()
 	config := loadConfig()
 
+	displayUnits = config.DisplayUnits
+
+	// serve the embedded templates and static files, unless told to use ones on disk. While
+	// developing, the ones in the working directory are used and templates are reparsed on every
+	// request, so edits show up without a rebuild.
+	development := os.Getenv("APP_ENV") == "development"
+	if flags.AssetsDir == "" && development {
+		flags.AssetsDir = "."
+	}
+
+	files, err := loadAssets(flags.AssetsDir)
+	if err != nil {
+		infoLogger.Println("Error loading assets:", err)
+		return
+	}
+
+	static := files.staticFiles()
+
+	pages, err = newTemplateRenderer(files.Templates, static, development && files.FromDisk)
+	if err != nil {
+		infoLogger.Println("Error parsing templates:", err)
+		return
+	}
+
 	// info
 	infoLogger.Printf("Starting Roofmail v%s", config.Version)
 	debugLogger.Println("Enabled")
 
+	// set up client, recording or replaying fixtures if HTTP_FIXTURES asks for it
+	transport, err := httpfixture.TransportFromEnv(http.DefaultTransport)
+	if err != nil {
+		infoLogger.Println("Error setting up HTTP fixtures:", err)
+		return
+	}
+
+	var client = http.Client{Transport: transport}
+
+	// create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
 	// Set the app location values
-	LATITUDE, err = strconv.ParseFloat(os.Getenv("LATITUDE"), 64)
+	geocoder := geocode.NewChainGeocoder(
+		geocode.NewNominatimGeocoder(&client, os.Getenv("GEOCODER_URL"), userAgent(config)),
+		geocode.NewZIPGeocoder(),
+	)
+
+	LATITUDE, LONGITUDE, err = resolveLocation(ctx, geocoder)
 	if err != nil {
-		infoLogger.Println("Error parsing latitude string to float:", err)
+		infoLogger.Println("Error resolving location:", err)
 		return
 	}
+	debugLogger.Printf("Using location %f,%f", LATITUDE, LONGITUDE)
 
-	LONGITUDE, err = strconv.ParseFloat(os.Getenv("LONGITUDE"), 64)
+	cache, err := newForecastCache()
 	if err != nil {
-		infoLogger.Println("Error parsing longitude string to float:", err)
+		infoLogger.Println("Error creating forecast cache:", err)
 		return
 	}
 
-	// set up client
-	var client = http.Client{}
-	w = wapi.NewWeatherGovAPI(&client, &LATITUDE, &LONGITUDE)
+	windowHistory, err = loadComfortHistory(os.Getenv("CACHE_DIR"))
+	if err != nil {
+		infoLogger.Println("Error loading comfort window history:", err)
+		return
+	}
 
-	// create a context
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	w, err = newWeatherAPI(os.Getenv("WEATHER_PROVIDER"), &client, config, cache)
+	if err != nil {
+		infoLogger.Println("Error creating Weather API:", err)
+		return
+	}
 
 	//initialize the API
 	err = w.InitForecastAPI(ctx, nil, nil)
+	var coverageErr *wapi.OutsideCoverageError
+	if errors.As(err, &coverageErr) {
+		infoLogger.Printf(
+			"Location %f,%f is outside weather.gov coverage, which only forecasts for the US. "+
+				"Use a location in the US or set WEATHER_PROVIDER=open-meteo to use Open-Meteo, which forecasts worldwide.",
+			coverageErr.Latitude,
+			coverageErr.Longitude,
+		)
+		return
+	}
 	if err != nil {
 		infoLogger.Panicln("Error initializing Weather API:", err)
 		return
 	}
 	ctx.Done()
 
+	forecasts = newForecastStore(w)
+	go runBackgroundRefresh(context.Background(), config.RefreshInterval)
+
 	router := gin.Default()
 	router.GET("/", indexHandler)
+	router.GET("/hourly", hourlyHandler)
+	router.GET("/week", outlookHandler)
+	router.GET("/calendar.ics", calendarHandler)
+	router.GET("/feed.atom", feedHandler)
+	router.GET("/events", eventsHandler)
 	router.GET("/like", getUserLike)
 	router.POST("/like", postUserLike)
-	router.Static("/static", "./static")
+	router.GET("/static/*filepath", static.Serve)
+	router.HEAD("/static/*filepath", static.Serve)
+	registerAPI(router)
+	router.NoRoute(notFoundHandler)
 	router.GET("/favicon.ico", func(c *gin.Context) {
-		c.File("static/favicon.ico")
+		c.FileFromFS("favicon.ico", http.FS(files.Static))
 	})
 
 	debugLogger.Println("Server running at http://localhost:8080/")
@@ -97,6 +198,34 @@ func main() {
 	}
 }
 
+// Run the fake weather.gov API for local development, e.g.
+//
+//	roofmail fakeapi -scenario storm -addr localhost:8081
+func runFakeAPI(args []string) error {
+	flags := flag.NewFlagSet("fakeapi", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:8081", "address to listen on")
+	scenario := flags.String("scenario", string(fakeapi.Normal), fmt.Sprintf("weather scenario, one of %v", fakeapi.Scenarios))
+	delay := flags.Duration("delay", 0, "delay before every response")
+	fixtures := flags.String("fixtures", "fixtures", "directory of recorded forecasts to serve")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	handler, err := fakeapi.NewHandler(fakeapi.Options{
+		Scenario:    fakeapi.Scenario(*scenario),
+		Delay:       *delay,
+		FixturesDir: *fixtures,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Fake weather.gov API (%s) running at http://%s/, set WEATHER_GOV_URL to use it", *scenario, *addr)
+	return http.ListenAndServe(*addr, handler)
+}
+
 // Initialize Logger
 func initLogs() {
 	// create log output
@@ -121,114 +250,266 @@ func initLogs() {
 	}
 }
 
-// Load configuration from environment variables or defaults
-func loadConfig() Config {
-	return Config{
-		Version: "0.0.0",
+// Resolve the app location.
+//
+// LATITUDE and LONGITUDE take precedence when set; otherwise LOCATION (an address or ZIP code) is
+// geocoded into coordinates.
+func resolveLocation(ctx context.Context, geocoder geocode.Geocoder) (float64, float64, error) {
+	latString := os.Getenv("LATITUDE")
+	longString := os.Getenv("LONGITUDE")
+
+	if latString != "" || longString != "" {
+		latitude, err := strconv.ParseFloat(latString, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parsing latitude string to float: %w", err)
+		}
+
+		longitude, err := strconv.ParseFloat(longString, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parsing longitude string to float: %w", err)
+		}
+
+		return latitude, longitude, nil
 	}
+
+	location := os.Getenv("LOCATION")
+	if location == "" {
+		return 0, 0, fmt.Errorf("set LOCATION or LATITUDE and LONGITUDE")
+	}
+
+	coordinates, err := geocoder.Geocode(ctx, location)
+	if err != nil {
+		return 0, 0, fmt.Errorf("geocoding %q: %w", location, err)
+	}
+
+	return coordinates.Latitude, coordinates.Longitude, nil
+}
+
+// Build the User-Agent for outgoing API requests. weather.gov and Nominatim both ask for contact
+// information, which is read from CONTACT.
+func userAgent(config Config) string {
+	contact := os.Getenv("CONTACT")
+	if contact == "" {
+		contact = "https://github.com/niclad/roofmail"
+	}
+
+	return fmt.Sprintf("roofmail/%s (%s)", config.Version, contact)
 }
 
-// Get the current temperature in Fahreneheit
-func getTempF(period wapi.Period) float64 {
-	tempF := period.Temperature.Value
+// Create the weather provider from WEATHER_PROVIDER, a comma-separated list of providers in
+// priority order. With more than one provider, later ones are used when earlier ones fail, or all
+// of them are blended when WEATHER_ENSEMBLE is true.
+func newWeatherAPI(providers string, client *http.Client, config Config, cache wapi.Cache) (wapi.WeatherAPI, error) {
+	names := strings.Split(providers, ",")
+	if len(names) == 1 {
+		return newProvider(strings.TrimSpace(names[0]), client, config, cache)
+	}
+
+	var named []wapi.NamedProvider
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		api, err := newProvider(name, client, config, cache)
+		if err != nil {
+			return nil, err
+		}
+
+		named = append(named, wapi.NamedProvider{Name: name, API: api})
+	}
 
-	if period.Temperature.UnitCode == "wmoUnit:degC" {
-		tempF = ctof(period.Temperature.Value)
+	mode := wapi.Failover
+	if ensemble, _ := strconv.ParseBool(os.Getenv("WEATHER_ENSEMBLE")); ensemble {
+		mode = wapi.Ensemble
 	}
 
-	return tempF
+	return wapi.NewCompositeAPI(mode, named...), nil
 }
 
-// Get the current Beaufort value
-func getBeaufort(period wapi.Period) int {
+// Create a single weather provider by name, defaulting to weather.gov
+func newProvider(provider string, client *http.Client, config Config, cache wapi.Cache) (wapi.WeatherAPI, error) {
+	switch provider {
+	case "", "weather.gov":
+		baseURL := os.Getenv("WEATHER_GOV_URL")
+		if baseURL == "" {
+			baseURL = wapi.BASE_URL
+		}
+
+		return wapi.NewWeatherGovAPI(client, &LATITUDE, &LONGITUDE,
+			wapi.WithBaseURL(baseURL),
+			wapi.WithUserAgent(userAgent(config)),
+			wapi.WithRateLimit(weatherGovLimiter),
+			wapi.WithTimeout(10*time.Second),
+			wapi.WithCache(cache),
+			wapi.WithLogger(debugLogger),
+		), nil
+	case "open-meteo":
+		return wapi.NewOpenMeteoAPI(client, &LATITUDE, &LONGITUDE, os.Getenv("OPEN_METEO_URL")), nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider %q, expected weather.gov or open-meteo", provider)
+	}
+}
 
-	return beaufortScale(*period.WindSpeed)
+// Create the forecast response cache, persisted to CACHE_DIR when it's set
+func newForecastCache() (wapi.Cache, error) {
+	dir := os.Getenv("CACHE_DIR")
+	if dir == "" {
+		return wapi.NewMemoryCache(), nil
+	}
+
+	return wapi.NewDiskCache(dir)
 }
 
-// Convert Celsius to Fahrenheit
-func ctof(c float64) float64 {
-	return (9 * c / 5) + 32
+// Load configuration from environment variables or defaults
+func loadConfig() Config {
+	displayUnits, err := units.ParseSystem(os.Getenv("DISPLAY_UNITS"))
+	if err != nil {
+		infoLogger.Println("Error parsing DISPLAY_UNITS, using imperial:", err)
+	}
+
+	refreshInterval := defaultRefreshInterval
+	if value := os.Getenv("REFRESH_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err == nil && interval > 0 {
+			refreshInterval = interval
+		} else {
+			infoLogger.Printf("Invalid REFRESH_INTERVAL %q, using %s", value, defaultRefreshInterval)
+		}
+	}
+
+	return Config{
+		Version:         "0.0.0",
+		DisplayUnits:    displayUnits,
+		RefreshInterval: refreshInterval,
+	}
 }
 
-// Convert KPH to MPH
-func kphToMph(kph float64) float64 {
-	return kph / 1.609344
+// Whether the weather looks comfortable
+type comfort int
+
+const (
+	comfortUnknown comfort = iota // the forecast is missing values needed to tell
+	comfortable
+	uncomfortable
+)
+
+func (c comfort) String() string {
+	switch c {
+	case comfortable:
+		return "comfortable"
+	case uncomfortable:
+		return "uncomfortable"
+	default:
+		return "unknown"
+	}
 }
 
-// Convert meters/s to miles/h
-func mpsToMph(mps float64) float64 {
-	return mps * 2.237
+// One of the values that decide whether the weather is comfortable
+type comfortFactor struct {
+	Name        string
+	Known       bool
+	Comfortable bool
 }
 
-// Determine arbitrary "comfort" value
-// Returns an `int`, where 0 is most comfortable and 10 is least comfortable.
+// Check each value the comfort logic uses against its comfortable range.
 // Note: This will probably have to be tuned
-func isComfortable(period wapi.Period) bool {
-	beaufort := getBeaufort(period)
-	temperature := getTempF(period)
-	percip := getPercipProb(period)
+func comfortFactors(c wapi.Conditions) []comfortFactor {
+	const minTempF = 75.0
+
+	temperature, hasTemperature := c.Temperature.Get()
+	windSpeed, hasWindSpeed := c.WindSpeed.Get()
+	percip, hasPercip := c.Precipitation.Get()
+
+	return []comfortFactor{
+		{Name: "temperature", Known: hasTemperature, Comfortable: temperature.Fahrenheit() >= minTempF},
+		{Name: "wind", Known: hasWindSpeed, Comfortable: beaufortScale(windSpeed) < 4},
+		{Name: "precipitation", Known: hasPercip, Comfortable: percip < 5.0},
+	}
+}
 
-	var minTempF = 75.0
+// Determine whether the weather is comfortable. Any known value outside the comfortable range
+// makes it uncomfortable; otherwise every value has to be known to call it comfortable.
+func assessComfort(c wapi.Conditions) comfort {
+	result := comfortable
+	for _, factor := range comfortFactors(c) {
+		switch {
+		case factor.Known && !factor.Comfortable:
+			return uncomfortable
+		case !factor.Known:
+			result = comfortUnknown
+		}
+	}
 
-	return (temperature >= minTempF && beaufort < 4 && percip < 5.0)
+	return result
 }
 
-func comfortMessage(period wapi.Period) string {
-	isComfy := isComfortable(period)
-	temp := getTempF(period)
-	beau := getBeaufort(period)
-	percip := getPercipProb(period)
+// Score the weather from 0 to 100 by the percentage of known factors in their comfortable range.
+// Unknown factors don't count either way, and with none known the score is 0.
+func comfortScore(factors []comfortFactor) int {
+	var known, score int
+	for _, factor := range factors {
+		if !factor.Known {
+			continue
+		}
+
+		known++
+		if factor.Comfortable {
+			score++
+		}
+	}
 
-	notStr := " not "
+	if known == 0 {
+		return 0
+	}
 
-	if isComfy {
-		notStr = " "
+	return score * 100 / known
+}
+
+func comfortMessage(c wapi.Conditions, system units.System) string {
+	var verdict string
+	switch assessComfort(c) {
+	case comfortable:
+		verdict = "It looks like the weather will be comfortable."
+	case uncomfortable:
+		verdict = "It looks like the weather will not be comfortable."
+	default:
+		verdict = "It's unknown whether the weather will be comfortable, the forecast is missing some details."
 	}
 
-	var percipModifier string = " "
+	temperature, hasTemperature := c.Temperature.Get()
+	windSpeed, hasWindSpeed := c.WindSpeed.Get()
 
+	var details string
 	switch {
-	case percip >= 50.0:
-		percipModifier += "high "
-	case percip >= 10.0:
-		break
-	case percip > 0.0:
-		percipModifier += "slight "
+	case hasTemperature && hasWindSpeed:
+		details = fmt.Sprintf(" The temperature is %s with a wind-level of %d (%s).",
+			temperature.Format(system), beaufortScale(windSpeed), windSpeed.Format(system))
+	case hasTemperature:
+		details = fmt.Sprintf(" The temperature is %s.", temperature.Format(system))
+	case hasWindSpeed:
+		details = fmt.Sprintf(" The wind-level is %d (%s).", beaufortScale(windSpeed), windSpeed.Format(system))
 	}
 
-	var percipMessage string = " There's a" + percipModifier + "chance of rain."
+	var percipMessage string
+	if percip, ok := c.Precipitation.Get(); ok && percip > 0.00001 {
+		var percipModifier string = " "
+
+		switch {
+		case percip >= 50.0:
+			percipModifier += "high "
+		case percip >= 10.0:
+			break
+		default:
+			percipModifier += "slight "
+		}
 
-	if percip <= 0.00001 {
-		percipMessage = ""
+		percipMessage = " There's a" + percipModifier + "chance of rain."
 	}
 
-	return fmt.Sprintf(
-		"It looks like the weather will%sbe comfortable. The temperature is %.0f\u00B0F with a wind-level of %d.%s",
-		notStr,
-		temp,
-		beau,
-		percipMessage,
-	)
+	return verdict + details + percipMessage
 }
 
 // Determine Beaufort value
-func beaufortScale(windSpeed wapi.WindSpeed) int {
-	var wind float64
-	if windSpeed.Value != nil {
-		wind = *windSpeed.Value
-	} else if windSpeed.MaxValue != nil {
-		wind = *windSpeed.MaxValue
-	} else {
-		return 0 // assuming no value means no wind
-	}
-
-	// convert to mph if kph
-	switch windSpeed.UnitCode {
-	case "wmoUnit:km_h-1":
-		wind = kphToMph(wind)
-	case "wmoUnit:m_s-1":
-		wind = mpsToMph(wind)
-	}
+func beaufortScale(windSpeed units.Speed) int {
+	wind := windSpeed.MilesPerHour()
 
 	switch {
 	case wind < 1.0:
@@ -246,46 +527,135 @@ func beaufortScale(windSpeed wapi.WindSpeed) int {
 	}
 }
 
-// Get the probability of percipitaion
-func getPercipProb(period wapi.Period) float64 {
-	return period.ProbabilityOfPrecipitation.Value
-}
-
+// PageData is the data for the index page
 type PageData struct {
 	Title       string
+	Page        string // which page the nav shows as current
 	Heading     string
 	Message     string
+	Comfort     string
 	RefreshDate string
+	Stale       bool
+	Units       string
 }
 
-func indexHandler(c *gin.Context) {
-	t, err := template.ParseFiles("templates/index.html")
-	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
-		return
+// Name of the cookie remembering a user's display units
+const unitsCookie = "units"
+
+// Determine the display units for a request. A `units` query parameter picks the units and is
+// remembered in a cookie for later visits; otherwise the cookie, then DISPLAY_UNITS, is used.
+func requestDisplayUnits(c *gin.Context) units.System {
+	system, fromQuery := displayUnitsFor(c)
+	if fromQuery {
+		c.SetCookie(unitsCookie, system.String(), 365*24*60*60, "/", "", false, true)
+	}
+
+	return system
+}
+
+// Determine the display units for a request the same way, without remembering them. Feeds and
+// calendars use this, since the apps subscribing to them don't keep cookies.
+func readDisplayUnits(c *gin.Context) units.System {
+	system, _ := displayUnitsFor(c)
+	return system
+}
+
+// Determine the display units for a request, and whether they came from the query
+func displayUnitsFor(c *gin.Context) (units.System, bool) {
+	if query, ok := c.GetQuery("units"); ok {
+		system, err := units.ParseSystem(query)
+		if err == nil {
+			return system, true
+		}
+
+		debugLogger.Println("Ignoring units query parameter:", err)
+	}
+
+	if cookie, err := c.Cookie(unitsCookie); err == nil {
+		system, err := units.ParseSystem(cookie)
+		if err == nil {
+			return system, false
+		}
+	}
+
+	return displayUnits, false
+}
+
+// Get the API units matching a display system, so forecast text uses the same units
+func apiUnits(system units.System) wapi.Units {
+	if system == units.Metric {
+		return wapi.SI
 	}
 
+	return wapi.US
+}
+
+func indexHandler(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	forecast, err := w.GetDailyForecast(ctx)
+	system := requestDisplayUnits(c)
+
+	result, err := forecasts.Daily(ctx, apiUnits(system))
 	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+		infoLogger.Println("Error getting daily forecast:", err)
+		c.String(http.StatusInternalServerError, forecastErrorMessage(err))
 		return
 	}
 
-	utcTime := time.Now().UTC()
-	utcString := utcTime.Format(time.RFC3339)
+	if result.Stale {
+		infoLogger.Println("Serving stale forecast, error getting daily forecast:", result.Err)
+	}
+
+	update, err := buildPageUpdate(result, system, time.Now())
+	if err != nil {
+		infoLogger.Println("Error reading daily forecast:", err)
+		c.String(http.StatusInternalServerError, "weather.gov returned an empty forecast.")
+		return
+	}
 
 	data := PageData{
 		Title:       "Roofmail",
-		Heading:     shortForecast(forecast.Periods[0]),
-		Message:     comfortMessage(forecast.Periods[0]),
-		RefreshDate: utcString,
+		Page:        "index",
+		Heading:     update.Heading,
+		Message:     update.Message,
+		Comfort:     update.Comfort,
+		RefreshDate: update.RefreshDate,
+		Stale:       update.Stale,
+		Units:       update.Units,
 	}
 
-	c.Status(http.StatusOK)
-	t.Execute(c.Writer, data)
+	pages.Render(c, "index", data)
+}
+
+// Describe when the page's data is from. That's when the page rendered, unless the forecast is
+// stale, in which case it's when the data was fetched.
+func refreshDate(result forecastResult, now time.Time) string {
+	if result.Stale {
+		return fmt.Sprintf("%s (%s, weather.gov is unavailable)", result.FetchedAt.UTC().Format(time.RFC3339), result.age(now))
+	}
+
+	return now.UTC().Format(time.RFC3339)
+}
+
+// Describe a forecast error for the error page
+func forecastErrorMessage(err error) string {
+	var apiErr *wapi.APIError
+	if !errors.As(err, &apiErr) {
+		return err.Error()
+	}
+
+	var summary string
+	switch {
+	case errors.Is(err, wapi.ErrRateLimited):
+		summary = "weather.gov is rate limiting requests, try again in a few minutes."
+	case errors.Is(err, wapi.ErrUnexpectedServerError):
+		summary = "weather.gov is having problems right now, try again later."
+	default:
+		summary = "weather.gov couldn't provide a forecast."
+	}
+
+	return fmt.Sprintf("%s\n\n%s", summary, apiErr.Error())
 }
 
 func shortForecast(period wapi.Period) string {
@@ -3,20 +3,31 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"roofmail/geocode"
+	"roofmail/units"
 	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
 )
 
 // --- Mock WeatherAPI ---
 
 type mockWeatherAPI struct {
-	initErr       error
-	forecastErr   error
-	dailyForecast wapi.DailyForecast
+	initErr        error
+	forecastErr    error
+	dailyForecast  wapi.DailyForecast
+	hourlyForecast wapi.HourlyForecast
 }
 
 func (m *mockWeatherAPI) InitForecastAPI(ctx context.Context, a, b *float64) error {
@@ -25,6 +36,9 @@ func (m *mockWeatherAPI) InitForecastAPI(ctx context.Context, a, b *float64) err
 func (m *mockWeatherAPI) GetDailyForecast(ctx context.Context, opts ...wapi.GetForcastOption) (wapi.DailyForecast, error) {
 	return m.dailyForecast, m.forecastErr
 }
+func (m *mockWeatherAPI) GetHourlyForecast(ctx context.Context, opts ...wapi.GetForcastOption) (wapi.HourlyForecast, error) {
+	return m.hourlyForecast, m.forecastErr
+}
 func (m *mockWeatherAPI) SetCoordinates(lat, lon *float64) {}
 
 // --- Helper functions ---
@@ -66,29 +80,6 @@ func captureOutput(f func()) string {
 
 // --- Tests ---
 
-func TestCtof(t *testing.T) {
-	setEnv("APP_ENV", "test")()
-	restore := mockLogs()
-	defer restore()
-
-	if got := ctof(0); got != 32 {
-		t.Errorf("ctof(0) = %v, want 32", got)
-	}
-	if got := ctof(100); got != 212 {
-		t.Errorf("ctof(100) = %v, want 212", got)
-	}
-}
-
-func TestKphToMph(t *testing.T) {
-	setEnv("APP_ENV", "test")()
-	restore := mockLogs()
-	defer restore()
-
-	if got := kphToMph(16.09344); got < 9.99 || got > 10.01 {
-		t.Errorf("kphToMph(16.09344) = %v, want ~10", got)
-	}
-}
-
 func TestBeaufortScale(t *testing.T) {
 	setEnv("APP_ENV", "test")()
 	restore := mockLogs()
@@ -104,53 +95,89 @@ func TestBeaufortScale(t *testing.T) {
 		{wapi.WindSpeed{Value: floatPtr(4.25), UnitCode: "wmoUnit:m_s-1"}, 3},
 		{wapi.WindSpeed{Value: floatPtr(6.5), UnitCode: "wmoUnit:m_s-1"}, 4},
 		{wapi.WindSpeed{Value: floatPtr(25), UnitCode: "wmoUnit:m_s-1"}, 5},
+		{wapi.WindSpeed{Value: floatPtr(16.09344), UnitCode: "wmoUnit:km_h-1"}, 3},
 		{wapi.WindSpeed{Value: nil, MaxValue: floatPtr(1.5), UnitCode: "wmoUnit:m_s-1"}, 1},
 		{wapi.WindSpeed{}, 0},
 	}
 	for _, tt := range tests {
-		if got := beaufortScale(tt.wind); got != tt.want {
+		c, err := wapi.Period{WindSpeed: &tt.wind}.Conditions()
+		if err != nil {
+			t.Errorf("Conditions(%v) unexpected error: %v", tt.wind, err)
+		}
+		speed, _ := c.WindSpeed.Get()
+		if got := beaufortScale(speed); got != tt.want {
 			t.Errorf("beaufortScale(%v) = %v, want %v", tt.wind, got, tt.want)
 		}
 	}
 }
 
-func TestGetTempF(t *testing.T) {
-	setEnv("APP_ENV", "test")()
-	restore := mockLogs()
-	defer restore()
-
-	period := wapi.Period{Temperature: &wapi.UnitValue{Value: 20, UnitCode: "wmoUnit:degC"}}
-	if got := getTempF(period); got < 67.9 || got > 68.1 {
-		t.Errorf("getTempF(C) = %v, want ~68", got)
-	}
-	period = wapi.Period{Temperature: &wapi.UnitValue{Value: 70, UnitCode: "wmoUnit:degF"}}
-	if got := getTempF(period); got != 70 {
-		t.Errorf("getTempF(F) = %v, want 70", got)
-	}
-}
-
-func TestIsComfortable(t *testing.T) {
+func TestAssessComfort(t *testing.T) {
 	setEnv("APP_ENV", "test")()
 	restore := mockLogs()
 	defer restore()
 
 	period := wapi.Period{
-		Temperature:                &wapi.UnitValue{Value: 30, UnitCode: "wmoUnit:degC"}, // 70F
+		Temperature:                &wapi.UnitValue{Value: 30, UnitCode: "wmoUnit:degC"}, // 86F
 		WindSpeed:                  &wapi.WindSpeed{Value: floatPtr(0.5), UnitCode: "wmoUnit:m_s-1"},
 		ProbabilityOfPrecipitation: &wapi.UnitValue{Value: 0.0, UnitCode: "wmoUnit:percent"},
 	}
 
-	if !isComfortable(period) {
-		t.Error("Expected comfortable")
+	assess := func() comfort {
+		t.Helper()
+		c, err := period.Conditions()
+		if err != nil {
+			t.Fatalf("Conditions() unexpected error: %v", err)
+		}
+		return assessComfort(c)
+	}
+
+	if got := assess(); got != comfortable {
+		t.Errorf("Expected comfortable, got %v", got)
+	}
+	period.ProbabilityOfPrecipitation.Null = true
+	if got := assess(); got != comfortUnknown {
+		t.Errorf("Expected unknown (no precipitation), got %v", got)
 	}
 	period.Temperature.Value = 10 // 50F
-	if isComfortable(period) {
-		t.Error("Expected not comfortable (too cold)")
+	if got := assess(); got != uncomfortable {
+		t.Errorf("Expected not comfortable (too cold), got %v", got)
 	}
 	period.Temperature.Value = 21.1
 	period.WindSpeed.Value = floatPtr(10) // high wind
-	if isComfortable(period) {
-		t.Error("Expected not comfortable (windy)")
+	if got := assess(); got != uncomfortable {
+		t.Errorf("Expected not comfortable (windy), got %v", got)
+	}
+	period.Temperature = nil
+	period.WindSpeed = nil
+	if got := assess(); got != comfortUnknown {
+		t.Errorf("Expected unknown (empty period), got %v", got)
+	}
+}
+
+func TestComfortScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		factors []comfortFactor
+		want    int
+	}{
+		{"none", nil, 0},
+		{"all unknown", []comfortFactor{{Name: "wind"}, {Name: "precipitation"}}, 0},
+		{"comfortable and unknown", []comfortFactor{{Name: "temperature", Known: true, Comfortable: true}, {Name: "wind"}}, 100},
+		{"one of two known", []comfortFactor{
+			{Name: "temperature", Known: true, Comfortable: true},
+			{Name: "wind", Known: true},
+			{Name: "precipitation"},
+		}, 50},
+		{"two of three", []comfortFactor{
+			{Name: "temperature", Known: true, Comfortable: true},
+			{Name: "wind", Known: true, Comfortable: true},
+			{Name: "precipitation", Known: true},
+		}, 66},
+	}
+	for _, tt := range tests {
+		if got := comfortScore(tt.factors); got != tt.want {
+			t.Errorf("%s: comfortScore() = %d, want %d", tt.name, got, tt.want)
+		}
 	}
 }
 
@@ -164,10 +191,30 @@ func TestComfortMessage(t *testing.T) {
 		WindSpeed:                  &wapi.WindSpeed{Value: floatPtr(2), UnitCode: "wmoUnit:m_s-1"},
 		ProbabilityOfPrecipitation: &wapi.UnitValue{Value: 32.1, UnitCode: "wmoUnit:percent"},
 	}
-	msg := comfortMessage(period)
+	c, err := period.Conditions()
+	if err != nil {
+		t.Fatalf("Conditions() unexpected error: %v", err)
+	}
+
+	msg := comfortMessage(c, units.Imperial)
 	if msg == "" || msg[0] != 'I' {
 		t.Errorf("comfortMessage = %q, want non-empty string", msg)
 	}
+	if !strings.Contains(msg, "70°F") || !strings.Contains(msg, "4 mph") {
+		t.Errorf("comfortMessage = %q, want imperial units", msg)
+	}
+
+	msg = comfortMessage(c, units.Metric)
+	if !strings.Contains(msg, "21°C") || !strings.Contains(msg, "7 km/h") {
+		t.Errorf("comfortMessage = %q, want metric units", msg)
+	}
+
+	// missing values are left out rather than shown as zero
+	c, _ = wapi.Period{Temperature: &wapi.UnitValue{Value: 30, UnitCode: "wmoUnit:degC"}}.Conditions()
+	msg = comfortMessage(c, units.Imperial)
+	if !strings.Contains(msg, "unknown") || !strings.Contains(msg, "86°F") || strings.Contains(msg, "wind") {
+		t.Errorf("comfortMessage = %q, want unknown comfort with only the temperature", msg)
+	}
 }
 
 func TestInitLogs(t *testing.T) {
@@ -190,6 +237,152 @@ func TestLoadConfig(t *testing.T) {
 	if cfg.Version != "0.0.0" {
 		t.Errorf("loadConfig() = %v, want version 0.0.0", cfg.Version)
 	}
+
+	unsetUnits := setEnv("DISPLAY_UNITS", "metric")
+	defer unsetUnits()
+	if cfg := loadConfig(); cfg.DisplayUnits != units.Metric {
+		t.Errorf("loadConfig() = %v, want metric display units", cfg.DisplayUnits)
+	}
+	if cfg.RefreshInterval != defaultRefreshInterval {
+		t.Errorf("loadConfig() = %v, want the default refresh interval", cfg.RefreshInterval)
+	}
+
+	unsetInterval := setEnv("REFRESH_INTERVAL", "90s")
+	defer unsetInterval()
+	if cfg := loadConfig(); cfg.RefreshInterval != 90*time.Second {
+		t.Errorf("loadConfig() = %v, want a 90s refresh interval", cfg.RefreshInterval)
+	}
+}
+
+func TestResolveLocation(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	unsetLat := setEnv("LATITUDE", "")
+	defer unsetLat()
+	unsetLon := setEnv("LONGITUDE", "")
+	defer unsetLon()
+	unsetLoc := setEnv("LOCATION", "10001")
+	defer unsetLoc()
+
+	lat, lon, err := resolveLocation(context.Background(), geocode.NewZIPGeocoder())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lat < 40 || lat > 41 || lon > -73 || lon < -74 {
+		t.Errorf("resolveLocation() = %v,%v, want a point in New York", lat, lon)
+	}
+
+	// explicit coordinates win over LOCATION
+	os.Setenv("LATITUDE", "39.5")
+	os.Setenv("LONGITUDE", "-75.0")
+	lat, lon, err = resolveLocation(context.Background(), geocode.NewZIPGeocoder())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lat != 39.5 || lon != -75.0 {
+		t.Errorf("resolveLocation() = %v,%v, want 39.5,-75", lat, lon)
+	}
+
+	os.Setenv("LATITUDE", "notafloat")
+	if _, _, err = resolveLocation(context.Background(), geocode.NewZIPGeocoder()); err == nil {
+		t.Error("Expected error parsing LATITUDE")
+	}
+}
+
+func TestForecastErrorMessage(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &wapi.APIError{Status: 503, Title: "Service Unavailable", CorrelationID: "abc123"})
+	msg := forecastErrorMessage(err)
+	if !strings.Contains(msg, "having problems") || !strings.Contains(msg, "abc123") {
+		t.Errorf("forecastErrorMessage() = %q, want server error summary with correlation ID", msg)
+	}
+
+	msg = forecastErrorMessage(errors.New("dial tcp: timeout"))
+	if msg != "dial tcp: timeout" {
+		t.Errorf("forecastErrorMessage() = %q, want plain error", msg)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	unsetContact := setEnv("CONTACT", "roof@example.com")
+	defer unsetContact()
+
+	if got := userAgent(Config{Version: "1.2.3"}); got != "roofmail/1.2.3 (roof@example.com)" {
+		t.Errorf("userAgent() = %q", got)
+	}
+
+	os.Setenv("CONTACT", "")
+	if got := userAgent(Config{Version: "1.2.3"}); !strings.Contains(got, "github.com/niclad/roofmail") {
+		t.Errorf("userAgent() = %q, want the repository as contact", got)
+	}
+}
+
+func TestNewWeatherAPI(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	for _, provider := range []string{"", "weather.gov", "open-meteo"} {
+		api, err := newWeatherAPI(provider, &http.Client{}, Config{}, wapi.NewMemoryCache())
+		if err != nil || api == nil {
+			t.Errorf("newWeatherAPI(%q) = %v, %v", provider, api, err)
+		}
+	}
+
+	if _, err := newWeatherAPI("accuweather", &http.Client{}, Config{}, nil); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+
+	api, err := newWeatherAPI("weather.gov, open-meteo", &http.Client{}, Config{}, wapi.NewMemoryCache())
+	if err != nil || api == nil {
+		t.Errorf("newWeatherAPI(list) = %v, %v", api, err)
+	}
+	if _, err := newWeatherAPI("weather.gov,accuweather", &http.Client{}, Config{}, nil); err == nil {
+		t.Error("Expected error for unknown provider in list")
+	}
+}
+
+func TestRequestDisplayUnits(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+	gin.SetMode(gin.TestMode)
+
+	displayUnits = units.Imperial
+	defer func() { displayUnits = units.Imperial }()
+
+	newContext := func(target string, cookie *http.Cookie) (*gin.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		return c, recorder
+	}
+
+	// the default
+	c, _ := newContext("/", nil)
+	if got := requestDisplayUnits(c); got != units.Imperial {
+		t.Errorf("requestDisplayUnits() = %v, want imperial", got)
+	}
+
+	// the query parameter wins and is remembered
+	c, recorder := newContext("/?units=metric", &http.Cookie{Name: unitsCookie, Value: "imperial"})
+	if got := requestDisplayUnits(c); got != units.Metric {
+		t.Errorf("requestDisplayUnits() = %v, want metric", got)
+	}
+	if cookie := recorder.Header().Get("Set-Cookie"); !strings.Contains(cookie, "units=metric") {
+		t.Errorf("expected units cookie, got %q", cookie)
+	}
+
+	// then the cookie
+	c, _ = newContext("/?units=bogus", &http.Cookie{Name: unitsCookie, Value: "metric"})
+	if got := requestDisplayUnits(c); got != units.Metric {
+		t.Errorf("requestDisplayUnits() = %v, want metric", got)
+	}
+
+	if apiUnits(units.Metric) != wapi.SI || apiUnits(units.Imperial) != wapi.US {
+		t.Error("apiUnits() doesn't match the display system")
+	}
 }
 
 // --- Integration-like test for main logic ---
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How long browsers may cache a static file requested by its content hash
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// staticFiles serves the static files under /static. Pages link to them with the file's content
+// hash in the URL, so browsers can keep them for good and still get the new file after a change.
+type staticFiles struct {
+	files fs.FS
+	cache bool // remember hashes, when the files can't change while running
+
+	mu     sync.Mutex
+	hashes map[string]string
+}
+
+func newStaticFiles(files fs.FS, cache bool) *staticFiles {
+	return &staticFiles{files: files, cache: cache, hashes: make(map[string]string)}
+}
+
+// Get the content hash of a static file
+func (s *staticFiles) hash(name string) (string, error) {
+	if s.cache {
+		s.mu.Lock()
+		hash, ok := s.hashes[name]
+		s.mu.Unlock()
+		if ok {
+			return hash, nil
+		}
+	}
+
+	data, err := fs.ReadFile(s.files, name)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:8])
+
+	if s.cache {
+		s.mu.Lock()
+		s.hashes[name] = hash
+		s.mu.Unlock()
+	}
+
+	return hash, nil
+}
+
+// Get the URL of a static file, with its content hash. Files that can't be read get a plain URL,
+// which 404s the same way.
+func (s *staticFiles) URL(name string) string {
+	hash, err := s.hash(name)
+	if err != nil {
+		debugLogger.Println("Linking to unreadable static file:", err)
+		return "/static/" + name
+	}
+
+	return "/static/" + name + "?v=" + hash
+}
+
+// GET /static/*filepath
+//
+// Requests with the file's current hash can be cached for good. Anything else, like the fonts
+// stylesheets load by relative URL, has to be revalidated, which the ETag makes cheap.
+func (s *staticFiles) Serve(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("filepath"), "/")
+
+	hash, err := s.hash(name)
+	if err != nil {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	if c.Query("v") == hash {
+		c.Header("Cache-Control", immutableCacheControl)
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+	c.Header("ETag", `"`+hash+`"`)
+
+	c.FileFromFS(name, http.FS(s.files))
+}
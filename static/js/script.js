@@ -2,44 +2,31 @@ console.info("Welcome to Roofmail!");
 
 let likeBtn = document.getElementById("like-btn");
 let dislikeBtn = document.getElementById("dislike-btn");
-let likeIcon = document.getElementById("like-icon");
-let dislikeIcon = document.getElementById("dislike-icon");
 
 function likeMouseEnter() {
     likeBtn.classList.remove("btn-primary");
     likeBtn.classList.add("btn-success");
-    likeIcon.classList.remove("bi-hand-thumbs-up");
-    likeIcon.classList.add("bi-hand-thumbs-up-fill");
 }
 
 function likeMouseLeave() {
     likeBtn.classList.remove("btn-success");
     likeBtn.classList.add("btn-primary");
-    likeIcon.classList.remove("bi-hand-thumbs-up-fill");
-    likeIcon.classList.add("bi-hand-thumbs-up");
 }
 
 function dislikeMouseEnter() {
     dislikeBtn.classList.remove("btn-primary");
     dislikeBtn.classList.add("btn-danger");
-    dislikeIcon.classList.remove("bi-hand-thumbs-down");
-    dislikeIcon.classList.add("bi-hand-thumbs-down-fill");
 }
 
 function dislikeMouseLeave() {
     dislikeBtn.classList.remove("btn-danger");
     dislikeBtn.classList.add("btn-primary");
-    dislikeIcon.classList.remove("bi-hand-thumbs-down-fill");
-    dislikeIcon.classList.add("bi-hand-thumbs-down");
 }
 
 function toggleLikeButton() {
     likeBtn.classList.remove("btn-success");
     likeBtn.classList.add("btn-primary");
 
-    likeIcon.classList.remove("bi-hand-thumbs-up-fill");
-    likeIcon.classList.add("bi-hand-thumbs-up");
-
     likeBtn.addEventListener("mouseenter", likeMouseEnter);
     likeBtn.addEventListener("mouseleave", likeMouseLeave);
 }
@@ -50,9 +37,6 @@ function toggleDislikeButton() {
     dislikeBtn.classList.remove("btn-danger");
     dislikeBtn.classList.add("btn-primary");
 
-    dislikeIcon.classList.remove("bi-hand-thumbs-down-fill");
-    dislikeIcon.classList.add("bi-hand-thumbs-down");
-
     dislikeBtn.addEventListener("mouseenter", dislikeMouseEnter);
     dislikeBtn.addEventListener("mouseleave", dislikeMouseLeave);
 }
@@ -96,8 +80,6 @@ likeBtn.addEventListener("click", () => {
 
     likeBtn.classList.remove("btn-primary");
     likeBtn.classList.add("btn-success");
-    likeIcon.classList.remove("bi-hand-thumbs-up");
-    likeIcon.classList.add("bi-hand-thumbs-up-fill");
 });
 
 dislikeBtn.addEventListener("click", () => {
@@ -110,6 +92,34 @@ dislikeBtn.addEventListener("click", () => {
 
     dislikeBtn.classList.remove("btn-primary");
     dislikeBtn.classList.add("btn-danger");
-    dislikeIcon.classList.remove("bi-hand-thumbs-down");
-    dislikeIcon.classList.add("bi-hand-thumbs-down-fill");
 });
+
+// Keep the forecast up to date while the page is open
+function patchForecast(update) {
+    document.getElementById("forecast-heading").textContent = update.heading;
+    document.getElementById("forecast-message").textContent = update.message;
+    document.getElementById("forecast").dataset.comfort = update.comfort;
+    document.getElementById("refresh-date").textContent = update.refreshDate;
+
+    let refresh = document.getElementById("refresh");
+    refresh.classList.toggle("text-danger", update.stale);
+    refresh.classList.toggle("text-black-50", !update.stale);
+}
+
+if (window.EventSource) {
+    let units = encodeURIComponent(document.body.dataset.units || "");
+    let events = new EventSource("/events?units=" + units);
+
+    events.addEventListener("forecast", event => {
+        try {
+            patchForecast(JSON.parse(event.data));
+        } catch (error) {
+            console.error("Error applying forecast update:", error);
+        }
+    });
+
+    // EventSource reconnects on its own; this is just for debugging
+    events.addEventListener("error", () => {
+        console.debug("Forecast updates disconnected, retrying.");
+    });
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testStaticFS() fstest.MapFS {
+	return fstest.MapFS{
+		"css/styles.css": {Data: []byte("body {}")},
+	}
+}
+
+func TestStaticFiles_URL(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	files := testStaticFS()
+	cached := newStaticFiles(files, true)
+	uncached := newStaticFiles(files, false)
+
+	url := cached.URL("css/styles.css")
+	if !strings.HasPrefix(url, "/static/css/styles.css?v=") || len(url) != len("/static/css/styles.css?v=")+16 {
+		t.Errorf("URL = %q, want the path with a content hash", url)
+	}
+	if uncached.URL("css/styles.css") != url {
+		t.Errorf("uncached URL = %q, want %q", uncached.URL("css/styles.css"), url)
+	}
+
+	files["css/styles.css"] = &fstest.MapFile{Data: []byte("body { color: red }")}
+	if cached.URL("css/styles.css") != url {
+		t.Error("expected the cached hash to stay the same")
+	}
+	if uncached.URL("css/styles.css") == url {
+		t.Error("expected the hash to change with the file")
+	}
+
+	if got := cached.URL("missing.css"); got != "/static/missing.css" {
+		t.Errorf("URL for a missing file = %q, want a plain URL", got)
+	}
+
+}
+
+func TestStaticFiles_Serve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	static := newStaticFiles(testStaticFS(), true)
+	router := gin.New()
+	router.GET("/static/*filepath", static.Serve)
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			request.Header[name] = values
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve(static.URL("css/styles.css"), nil)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "body {}" {
+		t.Fatalf("status = %d, body %q", recorder.Code, recorder.Body.String())
+	}
+	if got := recorder.Header().Get("Cache-Control"); got != immutableCacheControl {
+		t.Errorf("Cache-Control with the hash = %q, want %q", got, immutableCacheControl)
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("Content-Type = %q, want text/css", recorder.Header().Get("Content-Type"))
+	}
+
+	etag := recorder.Header().Get("ETag")
+	recorder = serve("/static/css/styles.css?v=old", nil)
+	if got := recorder.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control with an old hash = %q, want no-cache", got)
+	}
+
+	recorder = serve("/static/css/styles.css", http.Header{"If-None-Match": {etag}})
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("status when revalidating = %d, want 304", recorder.Code)
+	}
+
+	for _, path := range []string{"/static/missing.css", "/static/css", "/static/"} {
+		if recorder := serve(path, nil); recorder.Code != http.StatusNotFound {
+			t.Errorf("status for %s = %d, want 404", path, recorder.Code)
+		}
+	}
+}
+
+func TestStaticFiles_FromDisk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "static", "css", "styles.css")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("body {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// without APP_ENV=development, files from -assets can still change
+	files, err := loadAssets(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	static := files.staticFiles()
+	router := gin.New()
+	router.GET("/static/*filepath", static.Serve)
+
+	before := static.URL("css/styles.css")
+	if err := os.WriteFile(path, []byte("body { color: red }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	after := static.URL("css/styles.css")
+	if after == before {
+		t.Fatalf("expected the URL to change with the file, got %s both times", after)
+	}
+
+	// the old URL gets the new file, which mustn't be cached for good under it
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, before, nil))
+	if recorder.Body.String() != "body { color: red }" || recorder.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("old URL served %q with Cache-Control %q, want the new file and no-cache", recorder.Body.String(), recorder.Header().Get("Cache-Control"))
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, after, nil))
+	if recorder.Header().Get("Cache-Control") != immutableCacheControl {
+		t.Errorf("new URL Cache-Control = %q, want %q", recorder.Header().Get("Cache-Control"), immutableCacheControl)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The pages, each a template in the templates directory defining "content" and optionally "scripts"
+var pageNames = []string{"index", "hourly", "week"}
+
+// templateRenderer renders pages into the shared layout. Templates are parsed up front, and when
+// reload is set, again before every render so edits show up without a restart.
+type templateRenderer struct {
+	files  fs.FS
+	static *staticFiles // for linking to static files
+	reload bool
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// Page templates, set up in main
+var pages *templateRenderer
+
+// Create a renderer for the templates in files, which holds layout.html, partials/*.html, and a
+// template for each page
+func newTemplateRenderer(files fs.FS, static *staticFiles, reload bool) (*templateRenderer, error) {
+	r := &templateRenderer{files: files, static: static, reload: reload}
+
+	parsed, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	r.pages = parsed
+
+	return r, nil
+}
+
+// A link in the nav, and whether it's the page being shown
+type navItem struct {
+	Href    string
+	Label   string
+	Current bool
+}
+
+// Functions for the templates:
+//
+//	navLink .Page "index" "/" "Today"  a nav link, current when .Page is "index"
+//	static "css/styles.css"            the URL of a static file
+func (r *templateRenderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"navLink": func(current, page, href, label string) navItem {
+			return navItem{Href: href, Label: label, Current: current == page}
+		},
+		"static": r.static.URL,
+	}
+}
+
+// Parse the layout and partials with each page. Each page gets its own template set, since they
+// all define "content".
+func (r *templateRenderer) parse() (map[string]*template.Template, error) {
+	base, err := template.New("layout").Funcs(r.funcs()).ParseFS(r.files, "layout.html", "partials/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("parsing layout: %w", err)
+	}
+
+	parsed := make(map[string]*template.Template, len(pageNames))
+	for _, name := range pageNames {
+		page, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = page.ParseFS(r.files, name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s page: %w", name, err)
+		}
+
+		parsed[name] = page
+	}
+
+	return parsed, nil
+}
+
+// Get a page's template, reparsing them all first when reloading
+func (r *templateRenderer) lookup(name string) (*template.Template, error) {
+	if r.reload {
+		parsed, err := r.parse()
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		r.pages = parsed
+		r.mu.Unlock()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page, ok := r.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("no %s page template", name)
+	}
+
+	return page, nil
+}
+
+// Render a page with the layout. It's rendered in full before anything is written, so a template
+// error is sent as an error page rather than half a page.
+func (r *templateRenderer) Render(c *gin.Context, name string, data any) {
+	page, err := r.lookup(name)
+	if err != nil {
+		infoLogger.Println("Error loading templates:", err)
+		c.String(http.StatusInternalServerError, "Error loading page.")
+		return
+	}
+
+	var out bytes.Buffer
+	err = page.ExecuteTemplate(&out, "layout", data)
+	if err != nil {
+		infoLogger.Printf("Error rendering %s page: %v", name, err)
+		c.String(http.StatusInternalServerError, "Error rendering page.")
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", out.Bytes())
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Parse the embedded templates
+func testTemplates(t *testing.T) *templateRenderer {
+	t.Helper()
+
+	files, err := loadAssets("")
+	if err != nil {
+		t.Fatalf("loading assets: %v", err)
+	}
+
+	renderer, err := newTemplateRenderer(files.Templates, newStaticFiles(files.Static, true), false)
+	if err != nil {
+		t.Fatalf("parsing templates: %v", err)
+	}
+
+	return renderer
+}
+
+// A minimal template set, with a page that can be broken
+func testTemplateFS(index string) fstest.MapFS {
+	return fstest.MapFS{
+		"layout.html":       {Data: []byte(`{{ define "layout" }}<nav>{{ template "nav" . }}</nav>{{ template "content" . }}{{ end }}`)},
+		"partials/nav.html": {Data: []byte(`{{ define "nav" }}{{ with navLink .Page "index" "/" "Today" }}{{ if .Current }}current{{ end }}{{ end }}{{ end }}`)},
+		"index.html":        {Data: []byte(index)},
+		"hourly.html":       {Data: []byte(`{{ define "content" }}hourly{{ end }}`)},
+		"week.html":         {Data: []byte(`{{ define "content" }}week{{ end }}`)},
+	}
+}
+
+func renderPage(renderer *templateRenderer, name string, data any) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	renderer.Render(c, name, data)
+
+	return recorder
+}
+
+func TestTemplateRenderer(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	renderer := testTemplates(t)
+
+	recorder := renderPage(renderer, "index", PageData{Title: "Roofmail", Page: "index", Heading: "Sunny", Units: "imperial"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", recorder.Code, recorder.Body.String())
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, "<title>Roofmail</title>") || !strings.Contains(body, `<h1 id="forecast-heading">Sunny</h1>`) {
+		t.Errorf("expected the page in the layout, got %s", body)
+	}
+	if !strings.Contains(body, `href="/" aria-current="page"`) || strings.Contains(body, `href="/week" aria-current`) {
+		t.Errorf("expected Today to be the current page, got %s", body)
+	}
+	if !strings.Contains(body, `/static/js/script.js`) {
+		t.Errorf("expected the index page's scripts, got %s", body)
+	}
+
+	recorder = renderPage(renderer, "week", OutlookData{Title: "Roofmail — This week", Page: "week"})
+	body = recorder.Body.String()
+	if !strings.Contains(body, `href="/week" aria-current="page"`) || strings.Contains(body, `/static/js/script.js`) {
+		t.Errorf("expected This week to be the current page without the index scripts, got %s", body)
+	}
+
+	recorder = renderPage(renderer, "missing", nil)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status for a missing page = %d, want 500", recorder.Code)
+	}
+}
+
+func TestTemplateRenderer_VendoredAssets(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	files, err := loadAssets("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := newTemplateRenderer(files.Templates, newStaticFiles(files.Static, true), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pageData := map[string]any{
+		"index":  PageData{Page: "index"},
+		"hourly": TimelineData{Page: "hourly"},
+		"week":   OutlookData{Page: "week"},
+	}
+	for name, data := range pageData {
+		body := renderPage(renderer, name, data).Body.String()
+		if strings.Contains(body, "cdn.jsdelivr.net") {
+			t.Errorf("%s page links to a CDN: %s", name, body)
+		}
+
+		// linked with a hash, which they only get when they're embedded
+		for _, vendored := range []string{"/static/vendor/bootstrap/css/bootstrap.min.css?v=", "/static/vendor/bootstrap/js/bootstrap.bundle.min.js?v="} {
+			if !strings.Contains(body, vendored) {
+				t.Errorf("%s page doesn't link to %s, got %s", name, vendored, body)
+			}
+		}
+	}
+}
+
+func TestTemplateRenderer_ExecuteError(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	// calling a field on a string fails partway through the page
+	renderer, err := newTemplateRenderer(testTemplateFS(`{{ define "content" }}before {{ .Heading.Missing }}{{ end }}`), newStaticFiles(fstest.MapFS{}, true), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := renderPage(renderer, "index", PageData{Page: "index", Heading: "Sunny"})
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", recorder.Code)
+	}
+	if strings.Contains(recorder.Body.String(), "before") {
+		t.Errorf("expected no partial page, got %q", recorder.Body.String())
+	}
+}
+
+func TestTemplateRenderer_Reload(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	files := testTemplateFS(`{{ define "content" }}first{{ end }}`)
+
+	_, err := newTemplateRenderer(fstest.MapFS{"layout.html": files["layout.html"]}, newStaticFiles(fstest.MapFS{}, true), false)
+	if err == nil {
+		t.Error("expected an error for missing page templates")
+	}
+
+	cached, err := newTemplateRenderer(files, newStaticFiles(fstest.MapFS{}, true), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloading, err := newTemplateRenderer(files, newStaticFiles(fstest.MapFS{}, true), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files["index.html"] = &fstest.MapFile{Data: []byte(`{{ define "content" }}second{{ end }}`)}
+
+	if body := renderPage(cached, "index", PageData{Page: "index"}).Body.String(); body != "<nav>current</nav>first" {
+		t.Errorf("cached body = %q, want the templates as first parsed", body)
+	}
+	if body := renderPage(reloading, "index", PageData{Page: "index"}).Body.String(); body != "<nav>current</nav>second" {
+		t.Errorf("reloading body = %q, want the edited template", body)
+	}
+}
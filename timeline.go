@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"roofmail/units"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How many hours the timeline shows
+const timelineHours = 48
+
+// TimelineData is the data for the hourly timeline page
+type TimelineData struct {
+	Title       string
+	Page        string
+	Heading     string
+	Hours       []timelineHour
+	Windows     []string
+	RefreshDate string
+	Stale       bool
+	Units       string
+}
+
+// An hour on the timeline. Values the forecast doesn't include are shown as a dash.
+type timelineHour struct {
+	Time          string
+	Day           string
+	NewDay        bool // first hour of a day, which gets a heading
+	Temperature   string
+	Wind          string
+	Precipitation string
+	Comfort       string
+	Score         int
+	Hue           int // background hue, from red for a score of 0 to green for 100
+	InWindow      bool
+}
+
+// Shown in place of values missing from the forecast
+const missingValue = "—"
+
+// Build the timeline from the hourly periods that haven't ended yet, along with descriptions of the
+// comfortable windows in it
+func buildTimeline(periods []wapi.Period, now time.Time, system units.System) ([]timelineHour, []string) {
+	var upcoming []wapi.Period
+	for _, period := range periods {
+		if period.EndTime.After(now) {
+			upcoming = append(upcoming, period)
+		}
+		if len(upcoming) == timelineHours {
+			break
+		}
+	}
+
+	windows := comfortWindows(upcoming)
+
+	hours := make([]timelineHour, 0, len(upcoming))
+	for i, period := range upcoming {
+		conditions, err := period.Conditions()
+		if err != nil {
+			debugLogger.Printf("Reading conditions for hour %d: %v", period.Number, err)
+		}
+
+		score := comfortScore(comfortFactors(conditions))
+		hour := timelineHour{
+			Time:          period.StartTime.Format("3 PM"),
+			Day:           period.StartTime.Format("Monday, Jan 2"),
+			NewDay:        i == 0 || period.StartTime.YearDay() != upcoming[i-1].StartTime.YearDay(),
+			Temperature:   missingValue,
+			Wind:          missingValue,
+			Precipitation: missingValue,
+			Comfort:       assessComfort(conditions).String(),
+			Score:         score,
+			Hue:           score * 120 / 100,
+		}
+
+		if temperature, ok := conditions.Temperature.Get(); ok {
+			hour.Temperature = temperature.Format(system)
+		}
+		if wind, ok := conditions.WindSpeed.Get(); ok {
+			hour.Wind = wind.Format(system)
+		}
+		if percip, ok := conditions.Precipitation.Get(); ok {
+			hour.Precipitation = percip.Format()
+		}
+
+		for _, window := range windows {
+			if !period.StartTime.Before(window.Start) && !period.EndTime.After(window.End) {
+				hour.InWindow = true
+			}
+		}
+
+		hours = append(hours, hour)
+	}
+
+	descriptions := make([]string, 0, len(windows))
+	for _, window := range windows {
+		descriptions = append(descriptions, describeWindow(window.Start, window.End))
+	}
+
+	return hours, descriptions
+}
+
+// Describe a stretch of time, e.g. "Sat 2 PM – 6 PM" or "Sat 10 PM – Sun 2 AM"
+func describeWindow(start, end time.Time) string {
+	if start.YearDay() == end.YearDay() {
+		return start.Format("Mon 3 PM") + " – " + end.Format("3 PM")
+	}
+
+	return start.Format("Mon 3 PM") + " – " + end.Format("Mon 3 PM")
+}
+
+func hourlyHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	system := requestDisplayUnits(c)
+
+	result, err := forecasts.Hourly(ctx, apiUnits(system))
+	if err != nil {
+		infoLogger.Println("Error getting hourly forecast:", err)
+		c.String(http.StatusInternalServerError, forecastErrorMessage(err))
+		return
+	}
+
+	if result.Stale {
+		infoLogger.Println("Serving stale forecast, error getting hourly forecast:", result.Err)
+	}
+
+	now := time.Now()
+	hours, windows := buildTimeline(result.Forecast.Periods, now, system)
+
+	data := TimelineData{
+		Title:       "Roofmail — Next 48 hours",
+		Page:        "hourly",
+		Heading:     "The next 48 hours",
+		Hours:       hours,
+		Windows:     windows,
+		RefreshDate: refreshDate(result, now),
+		Stale:       result.Stale,
+		Units:       system.String(),
+	}
+
+	pages.Render(c, "hourly", data)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"roofmail/units"
+	wapi "roofmail/weatherAPI"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Hourly periods starting at midnight UTC on June 1st 2025, all comfortable except the given hours
+func testHourlyPeriods(count int, uncomfortableHours ...int) []wapi.Period {
+	periods := make([]wapi.Period, 0, count)
+	for hour := 0; hour < count; hour++ {
+		periods = append(periods, testPeriod(hour, 30, floatPtr(0)))
+	}
+	for _, hour := range uncomfortableHours {
+		periods[hour].Temperature.Value = 5
+	}
+	return periods
+}
+
+func TestBuildTimeline(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+
+	periods := testHourlyPeriods(60, 12, 13)
+	periods[20].ProbabilityOfPrecipitation = nil
+
+	// the first two hours are over
+	now := time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)
+	hours, windows := buildTimeline(periods, now, units.Imperial)
+
+	if len(hours) != timelineHours {
+		t.Fatalf("got %d hours, want %d", len(hours), timelineHours)
+	}
+	first := hours[0]
+	if first.Time != "2 AM" || !first.NewDay || first.Temperature != "86°F" || first.Precipitation != "0%" {
+		t.Errorf("unexpected first hour: %+v", first)
+	}
+	if first.Comfort != "comfortable" || first.Score != 100 || first.Hue != 120 || !first.InWindow {
+		t.Errorf("expected a comfortable first hour, got %+v", first)
+	}
+	if cold := hours[10]; cold.Comfort != "uncomfortable" || cold.InWindow || cold.Hue != 79 {
+		t.Errorf("expected an uncomfortable noon, got %+v", cold)
+	}
+	if missing := hours[18]; missing.Precipitation != missingValue || missing.Comfort != "unknown" || missing.InWindow {
+		t.Errorf("expected unknown comfort without precipitation, got %+v", missing)
+	}
+	if !hours[22].NewDay || hours[22].Day != "Monday, Jun 2" || hours[21].NewDay {
+		t.Errorf("expected a new day at midnight, got %+v and %+v", hours[21], hours[22])
+	}
+
+	want := []string{"Sun 2 AM – 12 PM", "Sun 2 PM – 8 PM", "Sun 9 PM – Tue 2 AM"}
+	if strings.Join(windows, "; ") != strings.Join(want, "; ") {
+		t.Errorf("windows = %q, want %q", windows, want)
+	}
+}
+
+func TestHourlyHandler(t *testing.T) {
+	restore := mockLogs()
+	defer restore()
+	gin.SetMode(gin.TestMode)
+
+	// start the forecast an hour ago so the first hour is still upcoming
+	start := time.Now().Truncate(time.Hour).Add(-time.Hour)
+	periods := testHourlyPeriods(3)
+	for i := range periods {
+		periods[i].StartTime = start.Add(time.Duration(i) * time.Hour)
+		periods[i].EndTime = periods[i].StartTime.Add(time.Hour)
+	}
+	forecasts = newForecastStore(&mockWeatherAPI{hourlyForecast: wapi.HourlyForecast{Periods: periods}})
+	pages = testTemplates(t)
+
+	router := gin.New()
+	router.GET("/hourly", hourlyHandler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/hourly?units=metric", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", recorder.Code, recorder.Body.String())
+	}
+	body := recorder.Body.String()
+	if strings.Count(body, `class="timeline-hour comfort-comfortable comfort-window"`) != 2 {
+		t.Errorf("expected two comfortable hours in a window, got %s", body)
+	}
+	if !strings.Contains(body, "30°C") || !strings.Contains(body, "hsl(120, 70%, 82%)") {
+		t.Errorf("expected metric values and comfort coloring, got %s", body)
+	}
+}
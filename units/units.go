@@ -0,0 +1,208 @@
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrUnknownUnit is returned when a unit code isn't recognized for the kind of quantity parsed.
+var ErrUnknownUnit = errors.New("unknown unit")
+
+// Temperature in degrees Celsius
+type Temperature float64
+
+// Speed in meters per second
+type Speed float64
+
+// Length in meters
+type Length float64
+
+// Percent from 0 to 100
+type Percent float64
+
+// Conversion factors to the base units
+const (
+	metersPerMile     = 1609.344
+	metersPerFoot     = 0.3048
+	metersPerInch     = 0.0254
+	metersPerNautical = 1852.0
+	secondsPerHour    = 3600.0
+)
+
+// Strip the namespace from a unit code, e.g. "wmoUnit:degC" becomes "degC"
+func unitName(unitCode string) string {
+	if _, name, ok := strings.Cut(unitCode, ":"); ok {
+		return name
+	}
+
+	return unitCode
+}
+
+// Build an error for an unrecognized unit code
+func unknownUnit(kind, unitCode string) error {
+	return fmt.Errorf("%w %q for %s", ErrUnknownUnit, unitCode, kind)
+}
+
+// ParseTemperature reads a temperature in one of the unit codes weather.gov emits.
+func ParseTemperature(unitCode string, value float64) (Temperature, error) {
+	switch unitName(unitCode) {
+	case "degC":
+		return Temperature(value), nil
+	case "degF":
+		return Temperature((value - 32) * 5 / 9), nil
+	case "K":
+		return Temperature(value - 273.15), nil
+	default:
+		return 0, unknownUnit("temperature", unitCode)
+	}
+}
+
+// In gives the temperature in one of the unit codes ParseTemperature reads.
+func (t Temperature) In(unitCode string) (float64, error) {
+	switch unitName(unitCode) {
+	case "degC":
+		return t.Celsius(), nil
+	case "degF":
+		return t.Fahrenheit(), nil
+	case "K":
+		return float64(t) + 273.15, nil
+	default:
+		return 0, unknownUnit("temperature", unitCode)
+	}
+}
+
+// ParseSpeed reads a speed in one of the unit codes weather.gov emits.
+func ParseSpeed(unitCode string, value float64) (Speed, error) {
+	switch unitName(unitCode) {
+	case "m_s-1":
+		return Speed(value), nil
+	case "km_h-1":
+		return Speed(value * 1000 / secondsPerHour), nil
+	case "mi_h-1", "[mi_i]/h":
+		return Speed(value * metersPerMile / secondsPerHour), nil
+	case "kn", "[kn_i]":
+		return Speed(value * metersPerNautical / secondsPerHour), nil
+	default:
+		return 0, unknownUnit("speed", unitCode)
+	}
+}
+
+// ParseLength reads a length in one of the unit codes weather.gov emits.
+func ParseLength(unitCode string, value float64) (Length, error) {
+	switch unitName(unitCode) {
+	case "m":
+		return Length(value), nil
+	case "km":
+		return Length(value * 1000), nil
+	case "cm":
+		return Length(value / 100), nil
+	case "mm":
+		return Length(value / 1000), nil
+	case "ft", "[ft_i]":
+		return Length(value * metersPerFoot), nil
+	case "in", "[in_i]":
+		return Length(value * metersPerInch), nil
+	case "mi", "[mi_i]":
+		return Length(value * metersPerMile), nil
+	default:
+		return 0, unknownUnit("length", unitCode)
+	}
+}
+
+// ParsePercent reads a percentage.
+func ParsePercent(unitCode string, value float64) (Percent, error) {
+	switch unitName(unitCode) {
+	case "percent", "%":
+		return Percent(value), nil
+	default:
+		return 0, unknownUnit("percent", unitCode)
+	}
+}
+
+func (t Temperature) Celsius() float64    { return float64(t) }
+func (t Temperature) Fahrenheit() float64 { return float64(t)*9/5 + 32 }
+
+func (s Speed) MetersPerSecond() float64   { return float64(s) }
+func (s Speed) KilometersPerHour() float64 { return float64(s) * secondsPerHour / 1000 }
+func (s Speed) MilesPerHour() float64      { return float64(s) * secondsPerHour / metersPerMile }
+func (s Speed) Knots() float64             { return float64(s) * secondsPerHour / metersPerNautical }
+
+func (l Length) Meters() float64      { return float64(l) }
+func (l Length) Kilometers() float64  { return float64(l) / 1000 }
+func (l Length) Millimeters() float64 { return float64(l) * 1000 }
+func (l Length) Feet() float64        { return float64(l) / metersPerFoot }
+func (l Length) Inches() float64      { return float64(l) / metersPerInch }
+func (l Length) Miles() float64       { return float64(l) / metersPerMile }
+
+// System is a set of units to display quantities in.
+type System int
+
+const (
+	Imperial System = iota
+	Metric
+)
+
+func (s System) String() string {
+	switch s {
+	case Metric:
+		return "metric"
+	default:
+		return "imperial"
+	}
+}
+
+// ParseSystem reads a display system name. "us" and "si" are accepted too, matching the
+// weather.gov units parameter.
+func ParseSystem(name string) (System, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "imperial", "us":
+		return Imperial, nil
+	case "metric", "si":
+		return Metric, nil
+	default:
+		return Imperial, fmt.Errorf("unknown unit system %q, expected imperial or metric", name)
+	}
+}
+
+// Format the temperature for display, e.g. "72°F" or "22°C"
+func (t Temperature) Format(system System) string {
+	if system == Metric {
+		return fmt.Sprintf("%.0f°C", roundZero(t.Celsius()))
+	}
+
+	return fmt.Sprintf("%.0f°F", roundZero(t.Fahrenheit()))
+}
+
+// Format the speed for display, e.g. "12 mph" or "19 km/h"
+func (s Speed) Format(system System) string {
+	if system == Metric {
+		return fmt.Sprintf("%.0f km/h", roundZero(s.KilometersPerHour()))
+	}
+
+	return fmt.Sprintf("%.0f mph", roundZero(s.MilesPerHour()))
+}
+
+// Format the length for display, e.g. "98 ft" or "30 m"
+func (l Length) Format(system System) string {
+	if system == Metric {
+		return fmt.Sprintf("%.0f m", roundZero(l.Meters()))
+	}
+
+	return fmt.Sprintf("%.0f ft", roundZero(l.Feet()))
+}
+
+// Format the percentage for display, e.g. "40%"
+func (p Percent) Format() string {
+	return fmt.Sprintf("%.0f%%", roundZero(float64(p)))
+}
+
+// Avoid printing "-0" for values that round to zero
+func roundZero(value float64) float64 {
+	if math.Abs(value) < 0.5 {
+		return 0
+	}
+
+	return value
+}
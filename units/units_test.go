@@ -0,0 +1,162 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func TestParseTemperature(t *testing.T) {
+	tests := []struct {
+		unitCode    string
+		value       float64
+		wantCelsius float64
+	}{
+		{"wmoUnit:degC", 0, 0},
+		{"wmoUnit:degC", 100, 100},
+		{"wmoUnit:degF", 32, 0},
+		{"wmoUnit:degF", 212, 100},
+		{"wmoUnit:K", 273.15, 0},
+		{"unit:degC", 20, 20},
+	}
+	for _, tt := range tests {
+		got, err := ParseTemperature(tt.unitCode, tt.value)
+		if err != nil {
+			t.Errorf("ParseTemperature(%q, %v) unexpected error: %v", tt.unitCode, tt.value, err)
+		}
+		if !near(got.Celsius(), tt.wantCelsius) {
+			t.Errorf("ParseTemperature(%q, %v) = %vC, want %vC", tt.unitCode, tt.value, got.Celsius(), tt.wantCelsius)
+		}
+
+		// and back again
+		back, err := got.In(tt.unitCode)
+		if err != nil || !near(back, tt.value) {
+			t.Errorf("In(%q) = %v, %v, want %v", tt.unitCode, back, err, tt.value)
+		}
+	}
+
+	if got := Temperature(100).Fahrenheit(); got != 212 {
+		t.Errorf("Fahrenheit() = %v, want 212", got)
+	}
+
+	_, err := ParseTemperature("wmoUnit:km_h-1", 10)
+	if !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("expected ErrUnknownUnit, got %v", err)
+	}
+
+	_, err = Temperature(10).In("wmoUnit:km_h-1")
+	if !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("expected ErrUnknownUnit from In, got %v", err)
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		unitCode string
+		value    float64
+		wantMph  float64
+	}{
+		{"wmoUnit:km_h-1", 16.09344, 10},
+		{"wmoUnit:m_s-1", 4.4704, 10},
+		{"wmoUnit:kn", 8.68976, 10},
+		{"wmoUnit:mi_h-1", 10, 10},
+	}
+	for _, tt := range tests {
+		got, err := ParseSpeed(tt.unitCode, tt.value)
+		if err != nil {
+			t.Errorf("ParseSpeed(%q, %v) unexpected error: %v", tt.unitCode, tt.value, err)
+		}
+		if !near(got.MilesPerHour(), tt.wantMph) {
+			t.Errorf("ParseSpeed(%q, %v) = %v mph, want %v", tt.unitCode, tt.value, got.MilesPerHour(), tt.wantMph)
+		}
+	}
+
+	if got := Speed(10).KilometersPerHour(); got != 36 {
+		t.Errorf("KilometersPerHour() = %v, want 36", got)
+	}
+
+	_, err := ParseSpeed("wmoUnit:degC", 10)
+	if !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("expected ErrUnknownUnit, got %v", err)
+	}
+}
+
+func TestParseLength(t *testing.T) {
+	tests := []struct {
+		unitCode   string
+		value      float64
+		wantMeters float64
+	}{
+		{"wmoUnit:m", 29.87, 29.87},
+		{"wmoUnit:km", 1.5, 1500},
+		{"wmoUnit:mm", 25.4, 0.0254},
+		{"wmoUnit:cm", 100, 1},
+		{"wmoUnit:ft", 100, 30.48},
+		{"wmoUnit:in", 1, 0.0254},
+		{"wmoUnit:mi", 1, 1609.344},
+	}
+	for _, tt := range tests {
+		got, err := ParseLength(tt.unitCode, tt.value)
+		if err != nil {
+			t.Errorf("ParseLength(%q, %v) unexpected error: %v", tt.unitCode, tt.value, err)
+		}
+		if !near(got.Meters(), tt.wantMeters) {
+			t.Errorf("ParseLength(%q, %v) = %v m, want %v", tt.unitCode, tt.value, got.Meters(), tt.wantMeters)
+		}
+	}
+
+	_, err := ParseLength("wmoUnit:percent", 1)
+	if !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("expected ErrUnknownUnit, got %v", err)
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	got, err := ParsePercent("wmoUnit:percent", 40)
+	if err != nil || got != 40 {
+		t.Errorf("ParsePercent() = %v, %v, want 40", got, err)
+	}
+
+	_, err = ParsePercent("wmoUnit:degC", 40)
+	if !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("expected ErrUnknownUnit, got %v", err)
+	}
+}
+
+func TestParseSystem(t *testing.T) {
+	tests := map[string]System{"": Imperial, "imperial": Imperial, "US": Imperial, "metric": Metric, " si ": Metric}
+	for name, want := range tests {
+		got, err := ParseSystem(name)
+		if err != nil || got != want {
+			t.Errorf("ParseSystem(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseSystem("kelvin"); err == nil {
+		t.Error("expected error for unknown system")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{Temperature(30).Format(Imperial), "86°F"},
+		{Temperature(30).Format(Metric), "30°C"},
+		{Temperature(-0.2).Format(Metric), "0°C"},
+		{Speed(4.4704).Format(Imperial), "10 mph"},
+		{Speed(10).Format(Metric), "36 km/h"},
+		{Length(30.48).Format(Imperial), "100 ft"},
+		{Length(30.48).Format(Metric), "30 m"},
+		{Percent(32.1).Format(), "32%"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package weatherAPI
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a stored response along with what's needed to decide whether it can be reused.
+type CacheEntry struct {
+	Body         []byte      `json:"body"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	Header       http.Header `json:"header"`
+	StoredAt     time.Time   `json:"storedAt"`
+	Expires      time.Time   `json:"expires"`
+}
+
+// Determine whether the entry can be served without revalidating
+func (e *CacheEntry) fresh(now time.Time) bool {
+	return now.Before(e.Expires)
+}
+
+// Cache stores API responses by request key.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry) error
+}
+
+// memoryCache is a Cache held in process memory.
+type memoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]*CacheEntry
+}
+
+// NewMemoryCache creates a Cache that lives for the lifetime of the process.
+func NewMemoryCache() Cache {
+	return &memoryCache{entries: make(map[string]*CacheEntry)}
+}
+
+func (c *memoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *memoryCache) Set(key string, entry *CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+	return nil
+}
+
+// diskCache is a memory cache backed by one JSON file per entry, so entries survive restarts.
+type diskCache struct {
+	memory *memoryCache
+	dir    string
+}
+
+// NewDiskCache creates a Cache persisted to the given directory, creating it if needed.
+func NewDiskCache(dir string) (Cache, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diskCache{
+		memory: NewMemoryCache().(*memoryCache),
+		dir:    dir,
+	}, nil
+}
+
+// Build the file path for a key
+func (c *diskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *diskCache) Get(key string) (*CacheEntry, bool) {
+	if entry, ok := c.memory.Get(key); ok {
+		return entry, true
+	}
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry CacheEntry
+	if json.Unmarshal(data, &entry) != nil {
+		// treat a corrupt file as a miss; the next Set overwrites it
+		return nil, false
+	}
+
+	c.memory.Set(key, &entry)
+	return &entry, true
+}
+
+func (c *diskCache) Set(key string, entry *CacheEntry) error {
+	c.memory.Set(key, entry)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so a crash never leaves a partial entry
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// Build the cache key for a request. Feature flags change the response shape, so they're part of
+// the key.
+func cacheKey(request *http.Request) string {
+	return request.Method + " " + request.URL.String() + " " + request.Header.Get("Feature-Flags")
+}
+
+// Determine when a response expires from its Cache-Control and Expires headers. The second
+// return value is false when the response must not be stored at all.
+func responseExpiry(header http.Header, now time.Time) (time.Time, bool) {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-store":
+			return time.Time{}, false
+		case "no-cache":
+			// storable, but must be revalidated every time
+			return now, true
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil {
+				continue
+			}
+
+			// account for time the response already spent in upstream caches
+			age, _ := strconv.Atoi(header.Get("Age"))
+			return now.Add(time.Duration(seconds-age) * time.Second), true
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		// an invalid date means already expired
+		date, err := http.ParseTime(expires)
+		if err != nil {
+			return now, true
+		}
+
+		return date, true
+	}
+
+	// no freshness information, but validators still allow revalidation
+	return now, true
+}
+
+// Build a response for a cached entry
+func cachedResponse(request *http.Request, entry *CacheEntry) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       request,
+	}
+}
+
+// Send a request, serving it from the cache when possible.
+//
+// Fresh entries are returned without a request, unless the request asks for no-cache. Stale entries
+// with an ETag or Last-Modified are revalidated, and a 304 response refreshes the entry. Only
+// successful GET responses are stored; a new response that can't be stored expires the old entry,
+// so it isn't served in its place.
+func (api *weatherGovAPI) do(request *http.Request) (*http.Response, error) {
+	if api.cache == nil || request.Method != http.MethodGet {
+		return api.doWithRetry(request)
+	}
+
+	key := cacheKey(request)
+	now := time.Now()
+
+	// a no-cache request still revalidates, so a changed response replaces the entry
+	entry, ok := api.cache.Get(key)
+	if ok && entry.fresh(now) && !strings.Contains(request.Header.Get("Cache-Control"), "no-cache") {
+		api.logger.Printf("cache hit for %s (expires in %s)", request.URL, entry.Expires.Sub(now).Round(time.Second))
+		return cachedResponse(request, entry), nil
+	}
+
+	if ok {
+		request = request.Clone(request.Context())
+		if entry.ETag != "" {
+			request.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			request.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	response, err := api.doWithRetry(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && response.StatusCode == http.StatusNotModified {
+		response.Body.Close()
+		api.logger.Printf("cache revalidated for %s", request.URL)
+
+		expires, storable := responseExpiry(response.Header, now)
+		if storable {
+			refreshed := *entry
+			refreshed.Expires = expires
+			refreshed.StoredAt = now
+			api.storeCacheEntry(key, &refreshed)
+		}
+
+		return cachedResponse(request, entry), nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return response, nil
+	}
+
+	expires, storable := responseExpiry(response.Header, now)
+	if !storable {
+		if ok {
+			expired := *entry
+			expired.Expires = now
+			api.storeCacheEntry(key, &expired)
+		}
+
+		return response, nil
+	}
+
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	api.logger.Printf("cache miss for %s (expires in %s)", request.URL, expires.Sub(now).Round(time.Second))
+	api.storeCacheEntry(key, &CacheEntry{
+		Body:         body,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		Header:       response.Header.Clone(),
+		StoredAt:     now,
+		Expires:      expires,
+	})
+
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
+
+// Store an entry, logging rather than failing the request if the cache can't be written
+func (api *weatherGovAPI) storeCacheEntry(key string, entry *CacheEntry) {
+	err := api.cache.Set(key, entry)
+	if err != nil {
+		api.logger.Println("Error writing cache entry:", err)
+	}
+}
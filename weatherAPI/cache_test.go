@@ -0,0 +1,154 @@
+package weatherAPI
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const cacheTestBody = `{"properties":{"units":"us","periods":[]}}`
+
+func newCachingAPI(t *testing.T, server *httptest.Server, cache Cache) *weatherGovAPI {
+	t.Helper()
+	lat, lon := 40.0, -75.0
+	api := NewWeatherGovAPI(server.Client(), &lat, &lon, WithCache(cache)).(*weatherGovAPI)
+	api.forecastProperties.Forecast = server.URL + "/forecast"
+	return api
+}
+
+func TestCache_ServesFreshResponses(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		w.Write([]byte(cacheTestBody))
+	}))
+	defer server.Close()
+
+	api := newCachingAPI(t, server, NewMemoryCache())
+	for i := 0; i < 3; i++ {
+		forecast, err := api.GetDailyForecast(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if forecast.Units != "us" {
+			t.Errorf("expected units 'us', got %v", forecast.Units)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 call, got %d", got)
+	}
+
+	// a different query is a different entry
+	_, err := api.GetDailyForecast(context.Background(), WithUnits(SI))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+}
+
+func TestCache_RevalidatesWithETag(t *testing.T) {
+	var calls, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=0")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(cacheTestBody))
+	}))
+	defer server.Close()
+
+	api := newCachingAPI(t, server, NewMemoryCache())
+	for i := 0; i < 2; i++ {
+		forecast, err := api.GetDailyForecast(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if forecast.Units != "us" {
+			t.Errorf("expected units 'us', got %v", forecast.Units)
+		}
+	}
+	if calls.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("expected 2 calls with 1 revalidation, got %d calls and %d revalidations", calls.Load(), notModified.Load())
+	}
+}
+
+func TestCache_RespectsNoStore(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(cacheTestBody))
+	}))
+	defer server.Close()
+
+	api := newCachingAPI(t, server, NewMemoryCache())
+	for i := 0; i < 2; i++ {
+		if _, err := api.GetDailyForecast(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+}
+
+func TestDiskCache_PersistsAcrossInstances(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.Write([]byte(cacheTestBody))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		cache, err := NewDiskCache(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		api := newCachingAPI(t, server, cache)
+		if _, err := api.GetDailyForecast(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 call, got %d", got)
+	}
+}
+
+func TestResponseExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		header   http.Header
+		want     time.Time
+		storable bool
+	}{
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=60"}}, now.Add(time.Minute), true},
+		{"max-age with age", http.Header{"Cache-Control": {"max-age=60"}, "Age": {"20"}}, now.Add(40 * time.Second), true},
+		{"max-age beats expires", http.Header{"Cache-Control": {"max-age=60"}, "Expires": {"Mon, 01 Jan 2024 01:00:00 GMT"}}, now.Add(time.Minute), true},
+		{"expires", http.Header{"Expires": {"Mon, 01 Jan 2024 01:00:00 GMT"}}, now.Add(time.Hour), true},
+		{"invalid expires", http.Header{"Expires": {"0"}}, now, true},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache"}}, now, true},
+		{"no-store", http.Header{"Cache-Control": {"no-store, max-age=60"}}, time.Time{}, false},
+		{"none", http.Header{}, now, true},
+	}
+	for _, tt := range tests {
+		got, storable := responseExpiry(tt.header, now)
+		if !got.Equal(tt.want) || storable != tt.storable {
+			t.Errorf("%s: responseExpiry() = %v, %v, want %v, %v", tt.name, got, storable, tt.want, tt.storable)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package weatherAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"roofmail/units"
+)
+
+// CompositeMode selects how a composite provider combines its providers.
+type CompositeMode int
+
+const (
+	// Failover uses the first provider, in priority order, that returns a forecast
+	Failover CompositeMode = iota
+	// Ensemble fetches every provider and blends their periods
+	Ensemble
+)
+
+// NamedProvider is a WeatherAPI with a name to report in Period.Sources.
+type NamedProvider struct {
+	Name string
+	API  WeatherAPI
+}
+
+// compositeAPI is an implementation of the WeatherAPI interface that spreads requests across
+// several providers.
+type compositeAPI struct {
+	mode      CompositeMode
+	providers []NamedProvider
+	// providers that initialized successfully; only these are asked for forecasts
+	ready []bool
+}
+
+// NewCompositeAPI creates a WeatherAPI over several providers, listed in priority order.
+//
+// In Failover mode, each request goes to the providers in order until one succeeds. In Ensemble
+// mode, every provider is asked and the periods of the first successful provider are blended with
+// matching periods from the others: the median temperature and the highest chance of precipitation
+// are used. Either way, Period.Sources lists the providers that contributed to each period.
+func NewCompositeAPI(mode CompositeMode, providers ...NamedProvider) WeatherAPI {
+	return &compositeAPI{
+		mode:      mode,
+		providers: providers,
+		ready:     make([]bool, len(providers)),
+	}
+}
+
+// Initialize every provider. Providers that fail are skipped for forecasts; an error is only
+// returned when none of them initialize.
+func (api *compositeAPI) InitForecastAPI(ctx context.Context, latitude, longitude *float64) error {
+	var errs []error
+	for i, provider := range api.providers {
+		err := provider.API.InitForecastAPI(ctx, latitude, longitude)
+		api.ready[i] = err == nil
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+		}
+	}
+
+	if len(errs) == len(api.providers) {
+		return errors.Join(append(errs, fmt.Errorf("no weather providers available"))...)
+	}
+
+	return nil
+}
+
+// Set the geographic coordinates for every provider to use
+func (api *compositeAPI) SetCoordinates(latitude, longitude *float64) {
+	for _, provider := range api.providers {
+		provider.API.SetCoordinates(latitude, longitude)
+	}
+}
+
+// Get the daily forecast from the providers
+func (api *compositeAPI) GetDailyForecast(ctx context.Context, opts ...GetForcastOption) (DailyForecast, error) {
+	return combine(ctx, api, func(p WeatherAPI) (DailyForecast, error) {
+		return p.GetDailyForecast(ctx, opts...)
+	})
+}
+
+// Get the hourly forecast from the providers
+func (api *compositeAPI) GetHourlyForecast(ctx context.Context, opts ...GetForcastOption) (HourlyForecast, error) {
+	forecast, err := combine(ctx, api, func(p WeatherAPI) (DailyForecast, error) {
+		hourly, err := p.GetHourlyForecast(ctx, opts...)
+		return DailyForecast(hourly), err
+	})
+
+	return HourlyForecast(forecast), err
+}
+
+// a forecast from one provider
+type providerForecast struct {
+	name     string
+	forecast DailyForecast
+}
+
+// Fetch forecasts from the ready providers and combine them according to the mode
+func combine(ctx context.Context, api *compositeAPI, fetch func(WeatherAPI) (DailyForecast, error)) (DailyForecast, error) {
+	var results []providerForecast
+	var errs []error
+
+	for i, provider := range api.providers {
+		if !api.ready[i] {
+			continue
+		}
+
+		forecast, err := fetch(provider.API)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+
+			// the caller gave up, so don't bother with the rest
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		results = append(results, providerForecast{name: provider.Name, forecast: forecast})
+		if api.mode == Failover {
+			break
+		}
+	}
+
+	if len(results) == 0 {
+		if len(errs) == 0 {
+			return DailyForecast{}, fmt.Errorf("no weather providers available")
+		}
+
+		return DailyForecast{}, errors.Join(errs...)
+	}
+
+	return blend(results), nil
+}
+
+// Blend the first forecast with matching periods from the rest
+func blend(results []providerForecast) DailyForecast {
+	primary := results[0]
+	forecast := primary.forecast
+	forecast.Periods = make([]Period, len(primary.forecast.Periods))
+
+	for i, period := range primary.forecast.Periods {
+		period.Sources = []string{primary.name}
+
+		temperatures := []*UnitValue{period.Temperature}
+		precipitation := period.ProbabilityOfPrecipitation
+
+		for _, other := range results[1:] {
+			match, ok := matchingPeriod(other.forecast.Periods, period)
+			if !ok {
+				continue
+			}
+
+			period.Sources = append(period.Sources, other.name)
+			temperatures = append(temperatures, match.Temperature)
+			if known(match.ProbabilityOfPrecipitation) &&
+				(!known(precipitation) || match.ProbabilityOfPrecipitation.Value > precipitation.Value) {
+				precipitation = match.ProbabilityOfPrecipitation
+			}
+		}
+
+		period.Temperature = medianTemperature(temperatures)
+		period.ProbabilityOfPrecipitation = precipitation
+		forecast.Periods[i] = period
+	}
+
+	return forecast
+}
+
+// Find the period covering the start of `target`, on the same side of day and night
+func matchingPeriod(periods []Period, target Period) (Period, bool) {
+	for _, period := range periods {
+		if period.IsDaytime != target.IsDaytime {
+			continue
+		}
+
+		if !period.StartTime.After(target.StartTime) && period.EndTime.After(target.StartTime) {
+			return period, true
+		}
+	}
+
+	return Period{}, false
+}
+
+// Find the median of the known temperatures, in the unit of the first one in a unit we can read
+func medianTemperature(temperatures []*UnitValue) *UnitValue {
+	var unitCode string
+	var values []units.Temperature
+
+	for _, temperature := range temperatures {
+		if !known(temperature) {
+			continue
+		}
+
+		value, err := units.ParseTemperature(temperature.UnitCode, temperature.Value)
+		if err != nil {
+			continue
+		}
+
+		if unitCode == "" {
+			unitCode = temperature.UnitCode
+		}
+		values = append(values, value)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	slices.Sort(values)
+	median := values[len(values)/2]
+	if len(values)%2 == 0 {
+		median = (values[len(values)/2-1] + median) / 2
+	}
+
+	// the unit code was just read, so it converts back
+	value, _ := median.In(unitCode)
+
+	return &UnitValue{UnitCode: unitCode, Value: value}
+}
+
+// Determine whether a value is present and not null
+func known(value *UnitValue) bool {
+	return value != nil && !value.Null
+}
@@ -0,0 +1,205 @@
+package weatherAPI
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubProvider is a WeatherAPI returning canned forecasts
+type stubProvider struct {
+	initErr  error
+	err      error
+	daily    DailyForecast
+	hourly   HourlyForecast
+	calls    int
+	lastOpts []GetForcastOption
+}
+
+func (s *stubProvider) InitForecastAPI(ctx context.Context, latitude, longitude *float64) error {
+	return s.initErr
+}
+
+func (s *stubProvider) SetCoordinates(latitude, longitude *float64) {}
+
+func (s *stubProvider) GetDailyForecast(ctx context.Context, opts ...GetForcastOption) (DailyForecast, error) {
+	s.calls++
+	s.lastOpts = opts
+	return s.daily, s.err
+}
+
+func (s *stubProvider) GetHourlyForecast(ctx context.Context, opts ...GetForcastOption) (HourlyForecast, error) {
+	s.calls++
+	return s.hourly, s.err
+}
+
+var compositeStart = time.Date(2024, 7, 2, 6, 0, 0, 0, time.UTC)
+
+func stubPeriod(offset time.Duration, length time.Duration, isDaytime bool, temperature *UnitValue, precipitation float64) Period {
+	return Period{
+		StartTime:                  compositeStart.Add(offset),
+		EndTime:                    compositeStart.Add(offset + length),
+		IsDaytime:                  isDaytime,
+		Temperature:                temperature,
+		ProbabilityOfPrecipitation: &UnitValue{UnitCode: "wmoUnit:percent", Value: precipitation},
+	}
+}
+
+func TestComposite_Failover(t *testing.T) {
+	primary := &stubProvider{err: errors.New("received status code 503")}
+	secondary := &stubProvider{daily: DailyForecast{Periods: []Period{{Name: "Today"}}}}
+	unused := &stubProvider{}
+
+	api := NewCompositeAPI(Failover,
+		NamedProvider{Name: "weather.gov", API: primary},
+		NamedProvider{Name: "open-meteo", API: secondary},
+		NamedProvider{Name: "other", API: unused},
+	)
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	forecast, err := api.GetDailyForecast(context.Background(), WithUnits(SI))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(forecast.Periods[0].Sources, []string{"open-meteo"}) {
+		t.Errorf("unexpected sources: %v", forecast.Periods[0].Sources)
+	}
+	if len(secondary.lastOpts) != 1 {
+		t.Errorf("expected options to be passed through, got %d", len(secondary.lastOpts))
+	}
+	if unused.calls != 0 {
+		t.Errorf("expected failover to stop at the first success, got %d calls", unused.calls)
+	}
+}
+
+func TestComposite_SkipsProvidersThatFailInit(t *testing.T) {
+	outside := &stubProvider{initErr: &OutsideCoverageError{Latitude: 51.5, Longitude: -0.12}}
+	worldwide := &stubProvider{hourly: HourlyForecast{Periods: []Period{{Number: 1}}}}
+
+	api := NewCompositeAPI(Failover,
+		NamedProvider{Name: "weather.gov", API: outside},
+		NamedProvider{Name: "open-meteo", API: worldwide},
+	)
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	forecast, err := api.GetHourlyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outside.calls != 0 || len(forecast.Periods) != 1 {
+		t.Errorf("expected only the initialized provider to be used, got %d calls", outside.calls)
+	}
+}
+
+func TestComposite_AllFail(t *testing.T) {
+	api := NewCompositeAPI(Failover,
+		NamedProvider{Name: "a", API: &stubProvider{err: ErrRateLimited}},
+		NamedProvider{Name: "b", API: &stubProvider{err: errors.New("b failed")}},
+	)
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := api.GetDailyForecast(context.Background())
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected joined errors including ErrRateLimited, got %v", err)
+	}
+
+	api = NewCompositeAPI(Failover, NamedProvider{Name: "a", API: &stubProvider{initErr: errors.New("nope")}})
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err == nil {
+		t.Fatal("expected error when no provider initializes")
+	}
+}
+
+func TestComposite_Ensemble(t *testing.T) {
+	day, night := 12*time.Hour, 12*time.Hour
+	weatherGov := &stubProvider{daily: DailyForecast{Units: "us", Periods: []Period{
+		stubPeriod(0, day, true, &UnitValue{UnitCode: "wmoUnit:degF", Value: 80}, 10),
+		stubPeriod(day, night, false, &UnitValue{UnitCode: "wmoUnit:degF", Value: 60}, 20),
+	}}}
+	openMeteo := &stubProvider{daily: DailyForecast{Periods: []Period{
+		// a whole day, starting at midnight
+		stubPeriod(-6*time.Hour, 24*time.Hour, true, &UnitValue{UnitCode: "wmoUnit:degC", Value: 30}, 40),
+	}}}
+	third := &stubProvider{daily: DailyForecast{Periods: []Period{
+		stubPeriod(0, day, true, &UnitValue{UnitCode: "wmoUnit:degF", Value: 84}, 5),
+		stubPeriod(day, night, false, nil, 70),
+	}}}
+
+	api := NewCompositeAPI(Ensemble,
+		NamedProvider{Name: "weather.gov", API: weatherGov},
+		NamedProvider{Name: "open-meteo", API: openMeteo},
+		NamedProvider{Name: "third", API: third},
+	)
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	forecast, err := api.GetDailyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if forecast.Units != "us" || len(forecast.Periods) != 2 {
+		t.Fatalf("expected the primary forecast's shape, got %+v", forecast)
+	}
+
+	today := forecast.Periods[0]
+	// 80F, 86F (30C) and 84F
+	if today.Temperature.Value != 84 || today.Temperature.UnitCode != "wmoUnit:degF" {
+		t.Errorf("expected median temperature of 84F, got %+v", today.Temperature)
+	}
+	if today.ProbabilityOfPrecipitation.Value != 40 {
+		t.Errorf("expected max precipitation of 40, got %v", today.ProbabilityOfPrecipitation.Value)
+	}
+	if !reflect.DeepEqual(today.Sources, []string{"weather.gov", "open-meteo", "third"}) {
+		t.Errorf("unexpected sources: %v", today.Sources)
+	}
+
+	// open-meteo has no night period, and the third provider has no temperature
+	tonight := forecast.Periods[1]
+	if tonight.Temperature.Value != 60 || tonight.ProbabilityOfPrecipitation.Value != 70 {
+		t.Errorf("unexpected night blend: %+v", tonight)
+	}
+	if !reflect.DeepEqual(tonight.Sources, []string{"weather.gov", "third"}) {
+		t.Errorf("unexpected sources: %v", tonight.Sources)
+	}
+
+	// the primary's periods are copied, not modified
+	if weatherGov.daily.Periods[0].Sources != nil {
+		t.Error("expected the provider's forecast to be left alone")
+	}
+}
+
+func TestMedianTemperature(t *testing.T) {
+	got := medianTemperature([]*UnitValue{
+		{UnitCode: "wmoUnit:km", Value: 1},
+		{UnitCode: "wmoUnit:degC", Value: 20},
+		{UnitCode: "wmoUnit:degF", Value: 50},
+		nil,
+		{UnitCode: "wmoUnit:K", Value: 273.15},
+	})
+	// 20C, 10C and 0C; a length isn't a temperature
+	if got == nil || got.Value != 10 || got.UnitCode != "wmoUnit:degC" {
+		t.Errorf("medianTemperature() = %+v, want 10C", got)
+	}
+
+	got = medianTemperature([]*UnitValue{
+		{UnitCode: "wmoUnit:degF", Value: 50},
+		{UnitCode: "wmoUnit:degC", Value: 20},
+	})
+	// 10C and 20C, in the first temperature's unit
+	if got == nil || math.Abs(got.Value-59) > 1e-9 || got.UnitCode != "wmoUnit:degF" {
+		t.Errorf("medianTemperature() = %+v, want 59F", got)
+	}
+
+	if medianTemperature([]*UnitValue{nil}) != nil {
+		t.Error("expected nil when no temperatures are known")
+	}
+}
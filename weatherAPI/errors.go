@@ -0,0 +1,100 @@
+package weatherAPI
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors for common API failures, for use with errors.Is
+var (
+	ErrNotFound              = errors.New("not found")
+	ErrRateLimited           = errors.New("rate limited")
+	ErrUnexpectedServerError = errors.New("unexpected server error")
+)
+
+// Problem type weather.gov reports for points it has no grid data for
+const invalidPointProblemType = "https://api.weather.gov/problems/InvalidPoint"
+
+// APIError is a failed API response, carrying the problem detail weather.gov sends as an
+// application/problem+json body. Only Status is guaranteed to be set.
+type APIError struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	Detail        string `json:"detail"`
+	Instance      string `json:"instance"`
+	CorrelationID string `json:"correlationId"`
+}
+
+func (e *APIError) Error() string {
+	if e.Title == "" {
+		return fmt.Sprintf("received status code %d", e.Status)
+	}
+
+	msg := fmt.Sprintf("received status code %d: %s", e.Status, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.CorrelationID != "" {
+		msg += fmt.Sprintf(" (correlation ID %s)", e.CorrelationID)
+	}
+
+	return msg
+}
+
+// Is matches the sentinel errors by status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrRateLimited:
+		return e.Status == http.StatusTooManyRequests
+	case ErrUnexpectedServerError:
+		return e.Status >= http.StatusInternalServerError
+	default:
+		return false
+	}
+}
+
+// OutsideCoverageError is returned when weather.gov has no forecast data for a point. This happens
+// for any location outside of the US and its territories.
+type OutsideCoverageError struct {
+	Latitude  float64
+	Longitude float64
+	Err       *APIError
+}
+
+func (e *OutsideCoverageError) Error() string {
+	msg := fmt.Sprintf("point %f,%f is outside weather.gov coverage", e.Latitude, e.Longitude)
+	if e.Err != nil && e.Err.Detail != "" {
+		msg += ": " + e.Err.Detail
+	}
+
+	return msg
+}
+
+func (e *OutsideCoverageError) Unwrap() error {
+	if e.Err == nil {
+		return nil
+	}
+
+	return e.Err
+}
+
+// Read the problem detail from a failed response. A body that isn't problem+json still produces
+// an error with the response status.
+func readAPIError(response *http.Response) *APIError {
+	apiErr := &APIError{}
+
+	// the body is best-effort; the status code is what matters
+	_ = readBody(response.Body, apiErr)
+
+	// trust the HTTP status over the one in the body
+	apiErr.Status = response.StatusCode
+	if apiErr.CorrelationID == "" {
+		apiErr.CorrelationID = response.Header.Get("X-Correlation-Id")
+	}
+
+	return apiErr
+}
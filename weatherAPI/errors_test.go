@@ -0,0 +1,90 @@
+package weatherAPI
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestAPIError_Error(t *testing.T) {
+	err := &APIError{Status: 404}
+	if err.Error() != "received status code 404" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = &APIError{Status: 500, Title: "Unexpected Problem", Detail: "An unexpected problem has occurred.", CorrelationID: "abc123"}
+	expected := "received status code 500: Unexpected Problem: An unexpected problem has occurred. (correlation ID abc123)"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestAPIError_Is(t *testing.T) {
+	tests := []struct {
+		status int
+		target error
+		want   bool
+	}{
+		{404, ErrNotFound, true},
+		{429, ErrRateLimited, true},
+		{500, ErrUnexpectedServerError, true},
+		{503, ErrUnexpectedServerError, true},
+		{404, ErrUnexpectedServerError, false},
+		{400, ErrNotFound, false},
+	}
+	for _, tt := range tests {
+		var err error = &APIError{Status: tt.status}
+		if got := errors.Is(err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%d, %v) = %v, want %v", tt.status, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestReadAPIError(t *testing.T) {
+	body := `{"correlationId":"abc123","title":"Unexpected Problem","type":"https://api.weather.gov/problems/UnexpectedProblem","status":500,"detail":"An unexpected problem has occurred.","instance":"https://api.weather.gov/requests/abc123"}`
+	response := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Header:     make(http.Header),
+	}
+
+	apiErr := readAPIError(response)
+	if apiErr.Status != http.StatusServiceUnavailable {
+		t.Errorf("expected the HTTP status to win, got %d", apiErr.Status)
+	}
+	if apiErr.Title != "Unexpected Problem" || apiErr.CorrelationID != "abc123" || apiErr.Instance == "" {
+		t.Errorf("unexpected problem fields: %+v", apiErr)
+	}
+}
+
+func TestReadAPIError_NotJSON(t *testing.T) {
+	response := &http.Response{
+		StatusCode: http.StatusBadGateway,
+		Body:       io.NopCloser(bytes.NewBufferString("<html>Bad Gateway</html>")),
+		Header:     http.Header{"X-Correlation-Id": []string{"xyz"}},
+	}
+
+	apiErr := readAPIError(response)
+	if apiErr.Status != http.StatusBadGateway || apiErr.CorrelationID != "xyz" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+}
+
+func TestGetDailyForecast_RateLimited(t *testing.T) {
+	lat, lon := 40.0, -75.0
+	client := newMockClient(`{"title":"Too Many Requests","status":429}`, http.StatusTooManyRequests)
+	api := NewWeatherGovAPI(client, &lat, &lon, WithRetryPolicy(RetryPolicy{MaxAttempts: 1})).(*weatherGovAPI)
+	api.forecastProperties.Forecast = "https://api.weather.gov/forecast"
+	_, err := api.GetDailyForecast(context.Background())
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Title != "Too Many Requests" {
+		t.Errorf("expected APIError with title, got %v", err)
+	}
+}
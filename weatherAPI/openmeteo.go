@@ -0,0 +1,374 @@
+package weatherAPI
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OPEN_METEO_URL is the default Open-Meteo forecast endpoint
+var OPEN_METEO_URL = "https://api.open-meteo.com/v1/forecast"
+
+// Variables requested from Open-Meteo, in the order they're mapped
+var (
+	openMeteoHourlyVariables = []string{
+		"temperature_2m",
+		"dew_point_2m",
+		"relative_humidity_2m",
+		"precipitation_probability",
+		"wind_speed_10m",
+		"wind_gusts_10m",
+		"wind_direction_10m",
+		"weather_code",
+		"is_day",
+	}
+	openMeteoDailyVariables = []string{
+		"temperature_2m_max",
+		"relative_humidity_2m_mean",
+		"precipitation_probability_max",
+		"wind_speed_10m_max",
+		"wind_gusts_10m_max",
+		"wind_direction_10m_dominant",
+		"weather_code",
+	}
+)
+
+// openMeteoAPI is an implementation of the WeatherAPI interface backed by Open-Meteo, which covers
+// the whole globe and needs no grid lookup.
+type openMeteoAPI struct {
+	client      *http.Client
+	baseURL     string
+	coordinates struct {
+		latitude  *float64
+		longitude *float64
+	}
+}
+
+// NewOpenMeteoAPI creates a WeatherAPI backed by the Open-Meteo forecast API. An empty baseURL
+// falls back to the public Open-Meteo endpoint.
+func NewOpenMeteoAPI(client *http.Client, latitude, longitude *float64, baseURL string) WeatherAPI {
+	if baseURL == "" {
+		baseURL = OPEN_METEO_URL
+	}
+
+	api := &openMeteoAPI{
+		client:  client,
+		baseURL: baseURL,
+	}
+	api.SetCoordinates(latitude, longitude)
+
+	return api
+}
+
+// the subset of an Open-Meteo forecast response that's mapped to periods
+type openMeteoResponse struct {
+	UTCOffsetSeconds int     `json:"utc_offset_seconds"`
+	Elevation        float64 `json:"elevation"`
+	Hourly           struct {
+		Time                     []int64    `json:"time"`
+		Temperature              []*float64 `json:"temperature_2m"`
+		Dewpoint                 []*float64 `json:"dew_point_2m"`
+		RelativeHumidity         []*float64 `json:"relative_humidity_2m"`
+		PrecipitationProbability []*float64 `json:"precipitation_probability"`
+		WindSpeed                []*float64 `json:"wind_speed_10m"`
+		WindGusts                []*float64 `json:"wind_gusts_10m"`
+		WindDirection            []*float64 `json:"wind_direction_10m"`
+		WeatherCode              []*int     `json:"weather_code"`
+		IsDay                    []*int     `json:"is_day"`
+	} `json:"hourly"`
+	Daily struct {
+		Time                     []int64    `json:"time"`
+		Temperature              []*float64 `json:"temperature_2m_max"`
+		RelativeHumidity         []*float64 `json:"relative_humidity_2m_mean"`
+		PrecipitationProbability []*float64 `json:"precipitation_probability_max"`
+		WindSpeed                []*float64 `json:"wind_speed_10m_max"`
+		WindGusts                []*float64 `json:"wind_gusts_10m_max"`
+		WindDirection            []*float64 `json:"wind_direction_10m_dominant"`
+		WeatherCode              []*int     `json:"weather_code"`
+	} `json:"daily"`
+}
+
+// the body Open-Meteo sends with a failed request
+type openMeteoError struct {
+	Reason string `json:"reason"`
+}
+
+// Initialize the API. Open-Meteo needs no lookup, so this only checks the coordinates.
+func (api *openMeteoAPI) InitForecastAPI(ctx context.Context, latitude, longitude *float64) error {
+	if latitude != nil && longitude != nil {
+		api.SetCoordinates(latitude, longitude)
+	}
+
+	if api.coordinates.latitude == nil || api.coordinates.longitude == nil {
+		return fmt.Errorf("no available latitude and longitude")
+	}
+
+	return ValidateCoordinates(*api.coordinates.latitude, *api.coordinates.longitude)
+}
+
+// Set the geographic coordinates for the API to use
+func (api *openMeteoAPI) SetCoordinates(latitude, longitude *float64) {
+	api.coordinates.latitude = latitude
+	api.coordinates.longitude = longitude
+}
+
+// Get the daily forecast, one daytime period per day for seven days.
+func (api *openMeteoAPI) GetDailyForecast(ctx context.Context, opts ...GetForcastOption) (DailyForecast, error) {
+	options := applyForecastOptions(opts)
+
+	var response openMeteoResponse
+	err := api.get(ctx, "daily", openMeteoDailyVariables, options, &response)
+	if err != nil {
+		return DailyForecast{}, err
+	}
+
+	location := time.FixedZone("", response.UTCOffsetSeconds)
+	daily := response.Daily
+	forecast := newOpenMeteoForecast(response, options)
+
+	for i, start := range daily.Time {
+		startTime := time.Unix(start, 0).In(location)
+
+		name := startTime.Weekday().String()
+		if i == 0 {
+			name = "Today"
+		}
+
+		forecast.Periods = append(forecast.Periods, Period{
+			Number:                     i + 1,
+			Name:                       name,
+			StartTime:                  startTime,
+			EndTime:                    startTime.AddDate(0, 0, 1),
+			IsDaytime:                  true,
+			Temperature:                temperatureValue(at(daily.Temperature, i), options.Units),
+			ProbabilityOfPrecipitation: percentValue(at(daily.PrecipitationProbability, i)),
+			RelativeHumidity:           percentValue(at(daily.RelativeHumidity, i)),
+			WindSpeed:                  windSpeedValue(at(daily.WindSpeed, i)),
+			WindGust:                   speedValue(at(daily.WindGusts, i)),
+			WindDirection:              compassDirection(at(daily.WindDirection, i)),
+			ShortForecast:              weatherCodeDescription(at(daily.WeatherCode, i)),
+		})
+	}
+
+	return forecast, nil
+}
+
+// Get the hourly forecast for the next seven days.
+func (api *openMeteoAPI) GetHourlyForecast(ctx context.Context, opts ...GetForcastOption) (HourlyForecast, error) {
+	options := applyForecastOptions(opts)
+
+	var response openMeteoResponse
+	err := api.get(ctx, "hourly", openMeteoHourlyVariables, options, &response)
+	if err != nil {
+		return HourlyForecast{}, err
+	}
+
+	location := time.FixedZone("", response.UTCOffsetSeconds)
+	hourly := response.Hourly
+	forecast := newOpenMeteoForecast(response, options)
+
+	for i, start := range hourly.Time {
+		startTime := time.Unix(start, 0).In(location)
+
+		isDay := at(hourly.IsDay, i)
+		forecast.Periods = append(forecast.Periods, Period{
+			Number:                     i + 1,
+			StartTime:                  startTime,
+			EndTime:                    startTime.Add(time.Hour),
+			IsDaytime:                  isDay != nil && *isDay == 1,
+			Temperature:                temperatureValue(at(hourly.Temperature, i), options.Units),
+			Dewpoint:                   temperatureValue(at(hourly.Dewpoint, i), options.Units),
+			ProbabilityOfPrecipitation: percentValue(at(hourly.PrecipitationProbability, i)),
+			RelativeHumidity:           percentValue(at(hourly.RelativeHumidity, i)),
+			WindSpeed:                  windSpeedValue(at(hourly.WindSpeed, i)),
+			WindGust:                   speedValue(at(hourly.WindGusts, i)),
+			WindDirection:              compassDirection(at(hourly.WindDirection, i)),
+			ShortForecast:              weatherCodeDescription(at(hourly.WeatherCode, i)),
+		})
+	}
+
+	return HourlyForecast(forecast), nil
+}
+
+// Request the given variables for one timestep ("hourly" or "daily")
+func (api *openMeteoAPI) get(ctx context.Context, timestep string, variables []string, options *GetForecastOptions, v any) error {
+	if api.coordinates.latitude == nil || api.coordinates.longitude == nil {
+		return fmt.Errorf("no available latitude and longitude")
+	}
+
+	u, err := url.Parse(api.baseURL)
+	if err != nil {
+		return err
+	}
+
+	temperatureUnit := "fahrenheit"
+	if options.Units == SI {
+		temperatureUnit = "celsius"
+	}
+
+	// create query string
+	params := url.Values{}
+	params.Add("latitude", fmt.Sprintf("%f", *api.coordinates.latitude))
+	params.Add("longitude", fmt.Sprintf("%f", *api.coordinates.longitude))
+	params.Add(timestep, strings.Join(variables, ","))
+	params.Add("temperature_unit", temperatureUnit)
+	params.Add("wind_speed_unit", "kmh")
+	params.Add("timeformat", "unixtime")
+	params.Add("timezone", "auto")
+	params.Add("forecast_days", "7")
+	u.RawQuery = params.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := api.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	// make sure the response is good
+	if response.StatusCode != http.StatusOK {
+		var body openMeteoError
+		_ = readBody(response.Body, &body)
+		return &APIError{Status: response.StatusCode, Title: "Open-Meteo request failed", Detail: body.Reason}
+	}
+
+	return readBody(response.Body, v)
+}
+
+// Set default values for options and apply the provided ones
+func applyForecastOptions(opts []GetForcastOption) *GetForecastOptions {
+	options := &GetForecastOptions{
+		Units:          US,
+		UseQuantValues: true,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// Build the forecast metadata shared by daily and hourly responses
+func newOpenMeteoForecast(response openMeteoResponse, options *GetForecastOptions) DailyForecast {
+	forecast := DailyForecast{
+		Units:             options.Units.String(),
+		ForecastGenerator: "Open-Meteo",
+		GeneratedAt:       time.Now().UTC(),
+		UpdateTime:        time.Now().UTC(),
+	}
+	forecast.Elevation.UnitCode = "wmoUnit:m"
+	forecast.Elevation.Value = response.Elevation
+
+	return forecast
+}
+
+// Get the i-th value of a series, or nil when it's missing
+func at[T any](values []*T, i int) *T {
+	if i >= len(values) {
+		return nil
+	}
+
+	return values[i]
+}
+
+func temperatureValue(value *float64, units Units) *UnitValue {
+	if value == nil {
+		return nil
+	}
+
+	unitCode := "wmoUnit:degF"
+	if units == SI {
+		unitCode = "wmoUnit:degC"
+	}
+
+	return &UnitValue{UnitCode: unitCode, Value: *value}
+}
+
+func percentValue(value *float64) *UnitValue {
+	if value == nil {
+		return nil
+	}
+
+	return &UnitValue{UnitCode: "wmoUnit:percent", Value: *value}
+}
+
+func speedValue(value *float64) *UnitValue {
+	if value == nil {
+		return nil
+	}
+
+	return &UnitValue{UnitCode: "wmoUnit:km_h-1", Value: *value}
+}
+
+func windSpeedValue(value *float64) *WindSpeed {
+	if value == nil {
+		return nil
+	}
+
+	speed := *value
+	return &WindSpeed{UnitCode: "wmoUnit:km_h-1", Value: &speed}
+}
+
+// Convert a bearing in degrees to a 16-point compass direction, like weather.gov uses
+func compassDirection(degrees *float64) string {
+	if degrees == nil {
+		return ""
+	}
+
+	directions := []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+	index := int((*degrees+11.25)/22.5) % len(directions)
+	if index < 0 {
+		index += len(directions)
+	}
+
+	return directions[index]
+}
+
+// Descriptions for WMO weather interpretation codes, as used by Open-Meteo
+var weatherCodeDescriptions = map[int]string{
+	0:  "Clear Sky",
+	1:  "Mainly Clear",
+	2:  "Partly Cloudy",
+	3:  "Overcast",
+	45: "Fog",
+	48: "Depositing Rime Fog",
+	51: "Light Drizzle",
+	53: "Drizzle",
+	55: "Dense Drizzle",
+	56: "Light Freezing Drizzle",
+	57: "Freezing Drizzle",
+	61: "Slight Rain",
+	63: "Rain",
+	65: "Heavy Rain",
+	66: "Light Freezing Rain",
+	67: "Freezing Rain",
+	71: "Slight Snow",
+	73: "Snow",
+	75: "Heavy Snow",
+	77: "Snow Grains",
+	80: "Slight Rain Showers",
+	81: "Rain Showers",
+	82: "Violent Rain Showers",
+	85: "Slight Snow Showers",
+	86: "Heavy Snow Showers",
+	95: "Thunderstorm",
+	96: "Thunderstorm With Slight Hail",
+	99: "Thunderstorm With Heavy Hail",
+}
+
+func weatherCodeDescription(code *int) string {
+	if code == nil {
+		return ""
+	}
+
+	return weatherCodeDescriptions[*code]
+}
@@ -0,0 +1,182 @@
+package weatherAPI
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const openMeteoHourlyBody = `{
+	"latitude": 51.5,
+	"longitude": -0.12,
+	"utc_offset_seconds": 3600,
+	"elevation": 23.0,
+	"hourly": {
+		"time": [1719961200, 1719964800],
+		"temperature_2m": [24.5, null],
+		"dew_point_2m": [12.1, 12.4],
+		"relative_humidity_2m": [45, 48],
+		"precipitation_probability": [0, 35],
+		"wind_speed_10m": [9.4, 12.0],
+		"wind_gusts_10m": [18.7, 22.3],
+		"wind_direction_10m": [225, 350],
+		"weather_code": [1, 61],
+		"is_day": [1, 0]
+	}
+}`
+
+const openMeteoDailyBody = `{
+	"utc_offset_seconds": -14400,
+	"daily": {
+		"time": [1719892800, 1719979200],
+		"temperature_2m_max": [81.3, 77.0],
+		"relative_humidity_2m_mean": [60, 55],
+		"precipitation_probability_max": [10, null],
+		"wind_speed_10m_max": [14.2, 20.1],
+		"wind_gusts_10m_max": [30.0, 35.5],
+		"wind_direction_10m_dominant": [180, 270],
+		"weather_code": [3, 95]
+	}
+}`
+
+func newOpenMeteoServer(t *testing.T, body string, gotQuery *url.Values) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gotQuery != nil {
+			*gotQuery = r.URL.Query()
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestOpenMeteo_GetHourlyForecast(t *testing.T) {
+	var query url.Values
+	server := newOpenMeteoServer(t, openMeteoHourlyBody, &query)
+	defer server.Close()
+
+	lat, lon := 51.5, -0.12
+	api := NewOpenMeteoAPI(server.Client(), &lat, &lon, server.URL)
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	forecast, err := api.GetHourlyForecast(context.Background(), WithUnits(SI))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query.Get("temperature_unit") != "celsius" || query.Get("latitude") != "51.500000" || query.Get("hourly") == "" {
+		t.Errorf("unexpected query: %v", query)
+	}
+	if len(forecast.Periods) != 2 {
+		t.Fatalf("expected 2 periods, got %d", len(forecast.Periods))
+	}
+
+	first := forecast.Periods[0]
+	if first.Temperature == nil || first.Temperature.Value != 24.5 || first.Temperature.UnitCode != "wmoUnit:degC" {
+		t.Errorf("unexpected temperature: %+v", first.Temperature)
+	}
+	if first.WindSpeed == nil || *first.WindSpeed.Value != 9.4 || first.WindSpeed.UnitCode != "wmoUnit:km_h-1" {
+		t.Errorf("unexpected wind speed: %+v", first.WindSpeed)
+	}
+	if first.WindGust == nil || first.WindGust.Value != 18.7 {
+		t.Errorf("unexpected wind gust: %+v", first.WindGust)
+	}
+	if first.RelativeHumidity.Value != 45 || first.ProbabilityOfPrecipitation.Value != 0 {
+		t.Errorf("unexpected humidity or precipitation: %+v", first)
+	}
+	if first.WindDirection != "SW" || first.ShortForecast != "Mainly Clear" || !first.IsDaytime {
+		t.Errorf("unexpected description: %+v", first)
+	}
+	if _, offset := first.StartTime.Zone(); offset != 3600 || first.EndTime.Sub(first.StartTime) != time.Hour {
+		t.Errorf("unexpected times: %v - %v", first.StartTime, first.EndTime)
+	}
+
+	second := forecast.Periods[1]
+	if second.Temperature != nil {
+		t.Errorf("expected missing temperature to be nil, got %+v", second.Temperature)
+	}
+	if second.WindDirection != "N" || second.IsDaytime {
+		t.Errorf("unexpected second period: %+v", second)
+	}
+}
+
+func TestOpenMeteo_GetDailyForecast(t *testing.T) {
+	var query url.Values
+	server := newOpenMeteoServer(t, openMeteoDailyBody, &query)
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	api := NewOpenMeteoAPI(server.Client(), &lat, &lon, server.URL)
+	forecast, err := api.GetDailyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query.Get("temperature_unit") != "fahrenheit" || query.Get("daily") == "" {
+		t.Errorf("unexpected query: %v", query)
+	}
+	if forecast.Units != "us" || len(forecast.Periods) != 2 {
+		t.Fatalf("unexpected forecast: %+v", forecast)
+	}
+
+	today, tomorrow := forecast.Periods[0], forecast.Periods[1]
+	if today.Name != "Today" || tomorrow.Name != "Wednesday" {
+		t.Errorf("unexpected names: %q, %q", today.Name, tomorrow.Name)
+	}
+	if today.Temperature.Value != 81.3 || today.Temperature.UnitCode != "wmoUnit:degF" {
+		t.Errorf("unexpected temperature: %+v", today.Temperature)
+	}
+	if tomorrow.ProbabilityOfPrecipitation != nil {
+		t.Errorf("expected missing precipitation to be nil, got %+v", tomorrow.ProbabilityOfPrecipitation)
+	}
+	if tomorrow.ShortForecast != "Thunderstorm" {
+		t.Errorf("unexpected short forecast: %q", tomorrow.ShortForecast)
+	}
+}
+
+func TestOpenMeteo_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":true,"reason":"Latitude must be in range of -90 to 90°."}`))
+	}))
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	api := NewOpenMeteoAPI(server.Client(), &lat, &lon, server.URL)
+	_, err := api.GetDailyForecast(context.Background())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest || apiErr.Detail == "" {
+		t.Fatalf("expected APIError with reason, got %v", err)
+	}
+}
+
+func TestOpenMeteo_InitValidatesCoordinates(t *testing.T) {
+	lat, lon := 40.0, 200.0
+	api := NewOpenMeteoAPI(&http.Client{}, &lat, &lon, "")
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err == nil {
+		t.Fatal("expected error for out of range longitude")
+	}
+}
+
+func TestCompassDirection(t *testing.T) {
+	tests := []struct {
+		degrees float64
+		want    string
+	}{
+		{0, "N"},
+		{11, "N"},
+		{12, "NNE"},
+		{90, "E"},
+		{359, "N"},
+		{202.5, "SSW"},
+	}
+	for _, tt := range tests {
+		if got := compassDirection(&tt.degrees); got != tt.want {
+			t.Errorf("compassDirection(%v) = %q, want %q", tt.degrees, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package weatherAPI
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"roofmail/units"
+)
+
+// ErrInvalidForecast is returned when a forecast payload can't be used.
+var ErrInvalidForecast = errors.New("invalid forecast")
+
+// UnmarshalJSON records whether the value was null, which weather.gov sends for values it doesn't
+// forecast, instead of reading it as zero.
+func (u *UnitValue) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		UnitCode string   `json:"unitCode"`
+		Value    *float64 `json:"value"`
+	}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	u.UnitCode = raw.UnitCode
+	u.Null = raw.Value == nil
+	u.Value = 0
+	if raw.Value != nil {
+		u.Value = *raw.Value
+	}
+
+	return nil
+}
+
+// MarshalJSON writes null values back out as null.
+func (u UnitValue) MarshalJSON() ([]byte, error) {
+	var value *float64
+	if !u.Null {
+		value = &u.Value
+	}
+
+	return json.Marshal(struct {
+		UnitCode string   `json:"unitCode"`
+		Value    *float64 `json:"value"`
+	}{u.UnitCode, value})
+}
+
+// Optional is a value that may be missing from a forecast.
+type Optional[T any] struct {
+	value T
+	ok    bool
+}
+
+// Some wraps a known value.
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{value: value, ok: true}
+}
+
+// Get returns the value and whether it's known.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.ok
+}
+
+// OK reports whether the value is known.
+func (o Optional[T]) OK() bool {
+	return o.ok
+}
+
+// MarshalJSON writes missing values as null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.ok {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON reads null as a missing value.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*o = Optional[T]{}
+		return nil
+	}
+
+	var value T
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	*o = Some(value)
+	return nil
+}
+
+// Conditions is the weather for a period, normalized to typed units. Values the forecast doesn't
+// include are missing rather than zero.
+type Conditions struct {
+	Temperature      Optional[units.Temperature] `json:"temperature"`
+	Dewpoint         Optional[units.Temperature] `json:"dewpoint"`
+	RelativeHumidity Optional[units.Percent]     `json:"relativeHumidity"`
+	Precipitation    Optional[units.Percent]     `json:"probabilityOfPrecipitation"`
+	WindSpeed        Optional[units.Speed]       `json:"windSpeed"`
+	WindGust         Optional[units.Speed]       `json:"windGust"`
+}
+
+// Conditions reads the period's weather into typed units.
+//
+// Values that are absent or null are missing. So are values in unknown units, which are also
+// reported in the returned error; the conditions are still usable when there's an error.
+func (p Period) Conditions() (Conditions, error) {
+	var c Conditions
+	var errs []error
+
+	c.Temperature = readUnitValue(p.Temperature, units.ParseTemperature, "temperature", &errs)
+	c.Dewpoint = readUnitValue(p.Dewpoint, units.ParseTemperature, "dewpoint", &errs)
+	c.RelativeHumidity = readUnitValue(p.RelativeHumidity, units.ParsePercent, "relative humidity", &errs)
+	c.Precipitation = readUnitValue(p.ProbabilityOfPrecipitation, units.ParsePercent, "probability of precipitation", &errs)
+	c.WindGust = readUnitValue(p.WindGust, units.ParseSpeed, "wind gust", &errs)
+
+	// use the top of the range when there's no single value
+	if p.WindSpeed != nil {
+		wind := p.WindSpeed.Value
+		if wind == nil {
+			wind = p.WindSpeed.MaxValue
+		}
+
+		if wind != nil {
+			speed, err := units.ParseSpeed(p.WindSpeed.UnitCode, *wind)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("wind speed: %w", err))
+			} else {
+				c.WindSpeed = Some(speed)
+			}
+		}
+	}
+
+	return c, errors.Join(errs...)
+}
+
+// Read an optional quantity, collecting unit errors
+func readUnitValue[T any](value *UnitValue, parse func(string, float64) (T, error), name string, errs *[]error) Optional[T] {
+	if value == nil || value.Null {
+		return Optional[T]{}
+	}
+
+	parsed, err := parse(value.UnitCode, value.Value)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s: %w", name, err))
+		return Optional[T]{}
+	}
+
+	return Some(parsed)
+}
+
+// Validate checks that every period has a usable time range. Missing weather values are allowed;
+// see Period.Conditions.
+func (f DailyForecast) Validate() error {
+	var errs []error
+	for i, period := range f.Periods {
+		switch {
+		case period.StartTime.IsZero() || period.EndTime.IsZero():
+			errs = append(errs, fmt.Errorf("period %d: missing start or end time", i+1))
+		case !period.EndTime.After(period.StartTime):
+			errs = append(errs, fmt.Errorf("period %d: ends at %s, before it starts at %s", i+1, period.EndTime, period.StartTime))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidForecast, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Validate checks that every period has a usable time range.
+func (f HourlyForecast) Validate() error {
+	return DailyForecast(f).Validate()
+}
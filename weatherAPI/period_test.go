@@ -0,0 +1,103 @@
+package weatherAPI
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"roofmail/units"
+)
+
+func TestUnitValue_Null(t *testing.T) {
+	var period Period
+	err := json.Unmarshal([]byte(`{"probabilityOfPrecipitation":{"unitCode":"wmoUnit:percent","value":null},"temperature":{"unitCode":"wmoUnit:degF","value":0}}`), &period)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !period.ProbabilityOfPrecipitation.Null {
+		t.Error("expected null precipitation to be marked Null")
+	}
+	if period.Temperature.Null {
+		t.Error("expected zero temperature not to be marked Null")
+	}
+
+	out, err := json.Marshal(period.ProbabilityOfPrecipitation)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != `{"unitCode":"wmoUnit:percent","value":null}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+}
+
+func TestPeriodConditions(t *testing.T) {
+	wind := 10.0
+	period := Period{
+		Temperature:                &UnitValue{UnitCode: "wmoUnit:degF", Value: 86},
+		ProbabilityOfPrecipitation: &UnitValue{UnitCode: "wmoUnit:percent", Null: true},
+		WindSpeed:                  &WindSpeed{UnitCode: "wmoUnit:km_h-1", MaxValue: &wind},
+		Dewpoint:                   &UnitValue{UnitCode: "wmoUnit:degRe", Value: 12},
+	}
+
+	c, err := period.Conditions()
+	if !errors.Is(err, units.ErrUnknownUnit) {
+		t.Errorf("expected ErrUnknownUnit for the dewpoint, got %v", err)
+	}
+	if temperature, ok := c.Temperature.Get(); !ok || temperature.Celsius() != 30 {
+		t.Errorf("Temperature = %v, %v; want 30°C", temperature, ok)
+	}
+	if speed, ok := c.WindSpeed.Get(); !ok || speed.KilometersPerHour() < 9.99 || speed.KilometersPerHour() > 10.01 {
+		t.Errorf("WindSpeed = %v, %v; want 10 km/h", speed, ok)
+	}
+	if c.Precipitation.OK() || c.Dewpoint.OK() || c.WindGust.OK() || c.RelativeHumidity.OK() {
+		t.Errorf("expected null, unreadable and absent values to be missing: %+v", c)
+	}
+
+	// an empty period has nothing to read but is not an error
+	c, err = Period{}.Conditions()
+	if err != nil || c.Temperature.OK() || c.WindSpeed.OK() {
+		t.Errorf("empty period: %+v, %v", c, err)
+	}
+
+	out, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != `{"temperature":null,"dewpoint":null,"relativeHumidity":null,"probabilityOfPrecipitation":null,"windSpeed":null,"windGust":null}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	var decoded Conditions
+	err = json.Unmarshal([]byte(`{"temperature":21.5,"windSpeed":null}`), &decoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if temperature, ok := decoded.Temperature.Get(); !ok || temperature != 21.5 || decoded.WindSpeed.OK() {
+		t.Errorf("unexpected decoded conditions: %+v", decoded)
+	}
+}
+
+func TestForecastValidate(t *testing.T) {
+	start := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	valid := Period{StartTime: start, EndTime: start.Add(12 * time.Hour)}
+
+	if err := (DailyForecast{}).Validate(); err != nil {
+		t.Errorf("empty forecast: unexpected error %v", err)
+	}
+	if err := (DailyForecast{Periods: []Period{valid}}).Validate(); err != nil {
+		t.Errorf("valid forecast: unexpected error %v", err)
+	}
+
+	tests := []Period{
+		{StartTime: start},
+		{StartTime: start, EndTime: start},
+		{StartTime: start, EndTime: start.Add(-time.Hour)},
+	}
+	for _, period := range tests {
+		err := HourlyForecast{Periods: []Period{valid, period}}.Validate()
+		if !errors.Is(err, ErrInvalidForecast) {
+			t.Errorf("Validate(%v - %v) expected ErrInvalidForecast, got %v", period.StartTime, period.EndTime, err)
+		}
+	}
+}
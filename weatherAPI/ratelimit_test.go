@@ -0,0 +1,50 @@
+package weatherAPI
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	limiter := NewRateLimiter(10, 2)
+	now := limiter.last
+
+	// the burst is available immediately
+	for i := 0; i < 2; i++ {
+		if got := limiter.reserve(now); got != 0 {
+			t.Errorf("reserve %d = %v, want 0", i, got)
+		}
+	}
+
+	// then requests queue at the configured rate
+	if got := limiter.reserve(now); got != 100*time.Millisecond {
+		t.Errorf("reserve = %v, want 100ms", got)
+	}
+	if got := limiter.reserve(now); got != 200*time.Millisecond {
+		t.Errorf("reserve = %v, want 200ms", got)
+	}
+
+	// tokens refill over time, up to the burst
+	if got := limiter.reserve(now.Add(time.Hour)); got != 0 {
+		t.Errorf("reserve after refill = %v, want 0", got)
+	}
+	if limiter.tokens != 1 {
+		t.Errorf("tokens = %v, want 1", limiter.tokens)
+	}
+}
+
+func TestRateLimiter_WaitCancelled(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
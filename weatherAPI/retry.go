@@ -0,0 +1,133 @@
+package weatherAPI
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+//
+// Delays grow exponentially from BaseDelay, capped at MaxDelay, with up to half of each delay
+// randomized so that concurrent clients don't retry in lockstep. A Retry-After header from the
+// server takes precedence when it asks for a longer wait.
+type RetryPolicy struct {
+	MaxAttempts int // total attempts, including the first; values below 1 mean a single attempt
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used when no policy is configured
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// Status codes weather.gov returns for transient failures
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// Determine whether a request outcome is worth retrying
+func shouldRetry(response *http.Response, err error) bool {
+	if err != nil {
+		// the caller gave up, so another attempt won't help
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	return retryableStatusCodes[response.StatusCode]
+}
+
+// Determine how long to wait before the next attempt. `attempt` counts from zero.
+func (p RetryPolicy) backoff(attempt int, response *http.Response) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		// shifting can overflow for large attempts
+		delay = p.MaxDelay
+	}
+
+	// equal jitter: keep half the delay, randomize the rest
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int64N(half+1))
+	}
+
+	if response != nil {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok && retryAfter > delay {
+			delay = min(retryAfter, p.MaxDelay)
+		}
+	}
+
+	return delay
+}
+
+// Parse a Retry-After header, which is either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// Send a request, retrying transient failures according to the retry policy.
+//
+// The request must not have a body. When all attempts fail with a retryable status, the last
+// response is returned so the caller can read the error from it.
+func (api *weatherGovAPI) doWithRetry(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	for attempt := 0; ; attempt++ {
+		response, err := api.send(request.Clone(ctx))
+		if attempt+1 >= api.retryPolicy.MaxAttempts || !shouldRetry(response, err) {
+			return response, err
+		}
+
+		delay := api.retryPolicy.backoff(attempt, response)
+
+		// release the connection before waiting
+		if response != nil {
+			_, _ = io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Send a single request attempt, waiting on the rate limiter first
+func (api *weatherGovAPI) send(request *http.Request) (*http.Response, error) {
+	if api.limiter != nil {
+		err := api.limiter.Wait(request.Context())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if api.userAgent != "" {
+		request.Header.Set("User-Agent", api.userAgent)
+	}
+
+	return api.client.Do(request)
+}
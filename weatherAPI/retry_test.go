@@ -0,0 +1,149 @@
+package weatherAPI
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetries = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// newFlakyServer fails the first `failures` requests with the given status
+func newFlakyServer(failures int32, status int, body string) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+
+	return server, &calls
+}
+
+func TestDo_RetriesTransientFailures(t *testing.T) {
+	body := `{"properties":{"units":"us","periods":[]}}`
+	server, calls := newFlakyServer(2, http.StatusServiceUnavailable, body)
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	api := NewWeatherGovAPI(server.Client(), &lat, &lon, WithRetryPolicy(fastRetries)).(*weatherGovAPI)
+	api.forecastProperties.Forecast = server.URL + "/forecast"
+
+	forecast, err := api.GetDailyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if forecast.Units != "us" {
+		t.Errorf("expected units 'us', got %v", forecast.Units)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 calls, got %d", got)
+	}
+}
+
+func TestDo_GivesUpAfterMaxAttempts(t *testing.T) {
+	server, calls := newFlakyServer(10, http.StatusInternalServerError, "")
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	api := NewWeatherGovAPI(server.Client(), &lat, &lon, WithRetryPolicy(fastRetries)).(*weatherGovAPI)
+	api.forecastProperties.ForecastHourly = server.URL + "/hourly"
+
+	_, err := api.GetHourlyForecast(context.Background())
+	if !errors.Is(err, ErrUnexpectedServerError) {
+		t.Fatalf("expected ErrUnexpectedServerError, got %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 calls, got %d", got)
+	}
+}
+
+func TestDo_DoesNotRetryClientErrors(t *testing.T) {
+	server, calls := newFlakyServer(10, http.StatusBadRequest, "")
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	api := NewWeatherGovAPI(server.Client(), &lat, &lon, WithRetryPolicy(fastRetries)).(*weatherGovAPI)
+	api.forecastProperties.Forecast = server.URL + "/forecast"
+
+	_, err := api.GetDailyForecast(context.Background())
+	if err == nil {
+		t.Fatal("expected error for bad status code")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected a single call, got %d", got)
+	}
+}
+
+func TestDo_StopsWhenContextCancelled(t *testing.T) {
+	server, calls := newFlakyServer(10, http.StatusServiceUnavailable, "")
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	api := NewWeatherGovAPI(server.Client(), &lat, &lon, WithRetryPolicy(policy)).(*weatherGovAPI)
+	api.forecastProperties.Forecast = server.URL + "/forecast"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := api.GetDailyForecast(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected a single call, got %d", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond, 400 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+		{100, 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		got := policy.backoff(tt.attempt, nil)
+		if got < tt.min || got > tt.max {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+		}
+	}
+
+	// Retry-After wins when it asks for longer, but is still capped
+	response := &http.Response{Header: http.Header{"Retry-After": []string{"30"}}}
+	if got := policy.backoff(0, response); got != time.Second {
+		t.Errorf("backoff with Retry-After = %v, want %v", got, time.Second)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"Mon, 01 Jan 2024 00:00:10 GMT", 10 * time.Second, true},
+		{"Sun, 31 Dec 2023 00:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -5,14 +5,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
 // GLOBALS
 var BASE_URL = "https://api.weather.gov"
 
+// DefaultUserAgent is sent when no User-Agent is configured. weather.gov asks that it identify the
+// application and include contact information.
+var DefaultUserAgent = "roofmail (https://github.com/niclad/roofmail)"
+
 type ForcastAPIResponse struct {
 	Properties ForecastAPIProps `json:"properties"`
 }
@@ -64,6 +72,7 @@ type WindSpeed struct {
 type UnitValue struct {
 	UnitCode string  `json:"unitCode"`
 	Value    float64 `json:"value"`
+	Null     bool    `json:"-"` // Set when the API sent a null value
 }
 
 // Period represents a forecast period, compatible with both daily and hourly responses
@@ -85,11 +94,13 @@ type Period struct {
 	Icon                       string     `json:"icon"`
 	ShortForecast              string     `json:"shortForecast"`
 	DetailedForecast           string     `json:"detailedForecast"`
+	Sources                    []string   `json:"sources,omitempty"` // Providers that contributed, set by composite providers
 }
 
-// WeatherAPI defines the interface for interacting with the weather.gov API.
+// WeatherAPI defines the interface for interacting with a weather forecast provider.
 type WeatherAPI interface {
 	GetDailyForecast(ctx context.Context, opts ...GetForcastOption) (DailyForecast, error)
+	GetHourlyForecast(ctx context.Context, opts ...GetForcastOption) (HourlyForecast, error)
 	InitForecastAPI(ctx context.Context, latitude, longitude *float64) error
 	SetCoordinates(latitude, longitude *float64)
 }
@@ -103,11 +114,80 @@ type weatherGovAPI struct {
 		longitude *float64
 	}
 	forecastProperties ForecastAPIProps
+	point              *resolvedPoint
+	retryPolicy        RetryPolicy
+	cache              Cache
+	logger             *log.Logger
+	userAgent          string
+	limiter            *RateLimiter
+	timeout            time.Duration
+
+	// guards forecastProperties and point, which can be refreshed while requests are in flight
+	mu sync.RWMutex
+}
+
+// The coordinates the forecast URLs were looked up for
+type resolvedPoint struct {
+	latitude  float64
+	longitude float64
+}
+
+// defines type for functional options on the client
+type ClientOption func(*weatherGovAPI)
+
+// Sets the policy for retrying failed requests
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(api *weatherGovAPI) {
+		api.retryPolicy = policy
+	}
+}
+
+// Sets the User-Agent sent with every request
+func WithUserAgent(userAgent string) ClientOption {
+	return func(api *weatherGovAPI) {
+		api.userAgent = userAgent
+	}
+}
+
+// Sets the rate limiter every request waits on. Pass the same limiter to several clients to limit
+// them together.
+func WithRateLimit(limiter *RateLimiter) ClientOption {
+	return func(api *weatherGovAPI) {
+		api.limiter = limiter
+	}
+}
+
+// Sets the base URL of the API, for example to point at a local stand-in
+func WithBaseURL(baseURL string) ClientOption {
+	return func(api *weatherGovAPI) {
+		api.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// Sets the timeout for each request attempt, including reading the response body
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(api *weatherGovAPI) {
+		api.timeout = timeout
+	}
+}
+
+// Sets the cache for API responses. Responses aren't cached unless a cache is provided.
+func WithCache(cache Cache) ClientOption {
+	return func(api *weatherGovAPI) {
+		api.cache = cache
+	}
+}
+
+// Sets the logger for client diagnostics, such as cache hits
+func WithLogger(logger *log.Logger) ClientOption {
+	return func(api *weatherGovAPI) {
+		api.logger = logger
+	}
 }
 
 // NewWeatherGovAPI creates a new instance of weatherGovAPI.
-func NewWeatherGovAPI(client *http.Client, latitude, longitude *float64) WeatherAPI {
-	return &weatherGovAPI{
+func NewWeatherGovAPI(client *http.Client, latitude, longitude *float64, opts ...ClientOption) WeatherAPI {
+	api := &weatherGovAPI{
 		client:  client,
 		baseURL: BASE_URL,
 		coordinates: struct {
@@ -117,7 +197,27 @@ func NewWeatherGovAPI(client *http.Client, latitude, longitude *float64) Weather
 			latitude:  latitude,
 			longitude: longitude,
 		},
+		retryPolicy: DefaultRetryPolicy,
+		logger:      log.New(io.Discard, "", 0),
+		userAgent:   DefaultUserAgent,
+	}
+
+	// Apply provided options
+	for _, opt := range opts {
+		opt(api)
+	}
+
+	// copy the client so the timeout doesn't leak to its other users
+	if api.timeout > 0 {
+		timeoutClient := http.Client{}
+		if api.client != nil {
+			timeoutClient = *api.client
+		}
+		timeoutClient.Timeout = api.timeout
+		api.client = &timeoutClient
 	}
+
+	return api
 }
 
 // Forecast represents the weather forecast data.
@@ -167,11 +267,24 @@ func WithUnits(units Units) GetForcastOption {
 }
 
 // Build the URL used for forecasts
-func buildForecastURL(latitude, longitude float64) string {
-	forecastResource := fmt.Sprintf("%s/points/%f,%f", BASE_URL, latitude, longitude)
+func buildForecastURL(baseURL string, latitude, longitude float64) string {
+	forecastResource := fmt.Sprintf("%s/points/%f,%f", baseURL, latitude, longitude)
 	return forecastResource
 }
 
+// Validate a latitude and longitude pair
+func ValidateCoordinates(latitude, longitude float64) error {
+	if math.IsNaN(latitude) || latitude < -90 || latitude > 90 {
+		return fmt.Errorf("latitude %v out of range [-90, 90]", latitude)
+	}
+
+	if math.IsNaN(longitude) || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("longitude %v out of range [-180, 180]", longitude)
+	}
+
+	return nil
+}
+
 // Initialize the API to fetch weather forecasts
 func (api *weatherGovAPI) InitForecastAPI(ctx context.Context, latitude, longitude *float64) error {
 	if api.coordinates.latitude == nil || api.coordinates.longitude == nil {
@@ -192,7 +305,18 @@ func (api *weatherGovAPI) InitForecastAPI(ctx context.Context, latitude, longitu
 		activeLong = *longitude
 	}
 
-	url := buildForecastURL(activeLat, activeLong)
+	err := ValidateCoordinates(activeLat, activeLong)
+	if err != nil {
+		return err
+	}
+
+	return api.resolvePoint(ctx, activeLat, activeLong, false)
+}
+
+// Look up the forecast URLs for a point. With `refresh`, any cached lookup is revalidated rather
+// than reused.
+func (api *weatherGovAPI) resolvePoint(ctx context.Context, latitude, longitude float64, refresh bool) error {
+	url := buildForecastURL(api.baseURL, latitude, longitude)
 
 	// create a request with a context
 	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -200,7 +324,11 @@ func (api *weatherGovAPI) InitForecastAPI(ctx context.Context, latitude, longitu
 		return err
 	}
 
-	response, err := api.client.Do(request)
+	if refresh {
+		request.Header.Set("Cache-Control", "no-cache")
+	}
+
+	response, err := api.do(request)
 	if err != nil {
 		return err
 	}
@@ -209,7 +337,14 @@ func (api *weatherGovAPI) InitForecastAPI(ctx context.Context, latitude, longitu
 
 	// make sure the response is good
 	if response.StatusCode != http.StatusOK {
-		return httpStatusError(response.StatusCode)
+		apiErr := readAPIError(response)
+
+		// points without grid data come back as an InvalidPoint problem
+		if apiErr.Type == invalidPointProblemType {
+			return &OutsideCoverageError{Latitude: latitude, Longitude: longitude, Err: apiErr}
+		}
+
+		return apiErr
 	}
 
 	var apiResponse ForcastAPIResponse
@@ -218,38 +353,37 @@ func (api *weatherGovAPI) InitForecastAPI(ctx context.Context, latitude, longitu
 		return err
 	}
 
+	api.mu.Lock()
 	api.forecastProperties = apiResponse.Properties
+	api.point = &resolvedPoint{latitude: latitude, longitude: longitude}
+	api.mu.Unlock()
 
 	// cool, no errors!
 	return nil
 }
 
-// Set the geographic coordinates for the API to use
-func (api *weatherGovAPI) SetCoordinates(latitude, longitude *float64) {
-	api.coordinates.latitude = latitude
-	api.coordinates.longitude = longitude
+// Get the resolved forecast URLs for the point
+func (api *weatherGovAPI) properties() ForecastAPIProps {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+
+	return api.forecastProperties
 }
 
-// Get the daily forecast for the configured latitude and longitude.
-//
-// The forecast is for a seven day period with weather results for that day and the "night" of that
-// day.
-func (api *weatherGovAPI) GetDailyForecast(ctx context.Context, opts ...GetForcastOption) (DailyForecast, error) {
-	// set default values for options
-	options := &GetForecastOptions{
-		Units:          US,
-		UseQuantValues: true,
-	}
+// Get the point the forecast URLs were looked up for, or nil before they have been
+func (api *weatherGovAPI) resolvedPoint() *resolvedPoint {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
 
-	// Apply provided options
-	for _, opt := range opts {
-		opt(options)
-	}
+	return api.point
+}
 
+// Build a request for one of the point's forecast endpoints
+func newForecastRequest(ctx context.Context, endpoint string, options *GetForecastOptions) (*http.Request, error) {
 	// parse base url
-	u, err := url.Parse(api.forecastProperties.Forecast)
+	u, err := url.Parse(endpoint)
 	if err != nil {
-		return DailyForecast{}, err
+		return nil, err
 	}
 
 	// create query string
@@ -259,10 +393,9 @@ func (api *weatherGovAPI) GetDailyForecast(ctx context.Context, opts ...GetForca
 	// add query to url
 	u.RawQuery = params.Encode()
 
-	var url = u.String()
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return DailyForecast{}, err
+		return nil, err
 	}
 
 	// set quantitative values header
@@ -270,7 +403,57 @@ func (api *weatherGovAPI) GetDailyForecast(ctx context.Context, opts ...GetForca
 		request.Header.Set("Feature-Flags", "forecast_temperature_qv,forecast_wind_speed_qv")
 	}
 
-	response, err := api.client.Do(request)
+	return request, nil
+}
+
+// Send a request to one of the point's forecast endpoints.
+//
+// NWS occasionally changes which grid a point maps to, after which the old forecast URLs 404 for
+// good. When that happens, the point the URLs came from is looked up again and the request retried
+// once against the refreshed URLs. The lookup goes through the cache like the first one, so the
+// refreshed URLs are what the next start up finds too.
+func (api *weatherGovAPI) doForecast(ctx context.Context, endpoint func(ForecastAPIProps) string, options *GetForecastOptions) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		request, err := newForecastRequest(ctx, endpoint(api.properties()), options)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := api.do(request)
+		if err != nil || response.StatusCode != http.StatusNotFound || attempt > 0 {
+			return response, err
+		}
+
+		// can't look the point up again if it never was
+		point := api.resolvedPoint()
+		if point == nil {
+			return response, nil
+		}
+
+		response.Body.Close()
+		api.logger.Printf("forecast %s not found, re-resolving grid point", request.URL)
+
+		err = api.resolvePoint(ctx, point.latitude, point.longitude, true)
+		if err != nil {
+			return nil, fmt.Errorf("re-resolving grid point: %w", err)
+		}
+	}
+}
+
+// Set the geographic coordinates for the API to use
+func (api *weatherGovAPI) SetCoordinates(latitude, longitude *float64) {
+	api.coordinates.latitude = latitude
+	api.coordinates.longitude = longitude
+}
+
+// Get the daily forecast for the configured latitude and longitude.
+//
+// The forecast is for a seven day period with weather results for that day and the "night" of that
+// day.
+func (api *weatherGovAPI) GetDailyForecast(ctx context.Context, opts ...GetForcastOption) (DailyForecast, error) {
+	options := applyForecastOptions(opts)
+
+	response, err := api.doForecast(ctx, func(p ForecastAPIProps) string { return p.Forecast }, options)
 	if err != nil {
 		return DailyForecast{}, err
 	}
@@ -279,7 +462,7 @@ func (api *weatherGovAPI) GetDailyForecast(ctx context.Context, opts ...GetForca
 
 	// make sure the response is good
 	if response.StatusCode != http.StatusOK {
-		return DailyForecast{}, httpStatusError(response.StatusCode)
+		return DailyForecast{}, readAPIError(response)
 	}
 
 	var dailyForecastResponse DailyForecastResponse
@@ -288,46 +471,18 @@ func (api *weatherGovAPI) GetDailyForecast(ctx context.Context, opts ...GetForca
 		return DailyForecast{}, err
 	}
 
-	return dailyForecastResponse.Properties, nil
-}
-
-func (api *weatherGovAPI) GetHourlyForecast(ctx context.Context, opts ...GetForcastOption) (HourlyForecast, error) {
-	// set default options
-	options := &GetForecastOptions{
-		Units:          US,
-		UseQuantValues: true,
-	}
-
-	// Apply provided options
-	for _, opt := range opts {
-		opt(options)
-	}
-
-	// parse base URL
-	u, err := url.Parse(api.forecastProperties.ForecastHourly)
+	err = dailyForecastResponse.Properties.Validate()
 	if err != nil {
-		return HourlyForecast{}, err
+		return DailyForecast{}, err
 	}
 
-	// create quert string
-	params := url.Values{}
-	params.Add("units", options.Units.String())
-
-	// add query to URL
-	u.RawQuery = params.Encode()
-
-	url := u.String()
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return HourlyForecast{}, nil
-	}
+	return dailyForecastResponse.Properties, nil
+}
 
-	// set quant header
-	if options.UseQuantValues {
-		request.Header.Set("Feature-Flags", "forecast_temperature_qv,forecast_wind_speed_qv")
-	}
+func (api *weatherGovAPI) GetHourlyForecast(ctx context.Context, opts ...GetForcastOption) (HourlyForecast, error) {
+	options := applyForecastOptions(opts)
 
-	response, err := api.client.Do(request)
+	response, err := api.doForecast(ctx, func(p ForecastAPIProps) string { return p.ForecastHourly }, options)
 	if err != nil {
 		return HourlyForecast{}, err
 	}
@@ -336,7 +491,7 @@ func (api *weatherGovAPI) GetHourlyForecast(ctx context.Context, opts ...GetForc
 
 	// make sure the response is good
 	if response.StatusCode != http.StatusOK {
-		return HourlyForecast{}, httpStatusError(response.StatusCode)
+		return HourlyForecast{}, readAPIError(response)
 	}
 
 	var hourlyForecastResponse hourlyForecastResponse
@@ -345,12 +500,12 @@ func (api *weatherGovAPI) GetHourlyForecast(ctx context.Context, opts ...GetForc
 		return HourlyForecast{}, err
 	}
 
-	return hourlyForecastResponse.Properties, nil
-}
+	err = hourlyForecastResponse.Properties.Validate()
+	if err != nil {
+		return HourlyForecast{}, err
+	}
 
-// create an HTTP status error
-func httpStatusError(statusCode int) error {
-	return fmt.Errorf("received status code %d", statusCode)
+	return hourlyForecastResponse.Properties, nil
 }
 
 // Read a response body
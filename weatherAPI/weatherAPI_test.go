@@ -3,9 +3,17 @@ package weatherAPI
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"roofmail/httpfixture"
 )
 
 // mockRoundTripper implements http.RoundTripper for mocking HTTP responses
@@ -41,7 +49,7 @@ func TestWithUnits(t *testing.T) {
 func TestBuildForecastURL(t *testing.T) {
 	lat, lon := 40.0, -75.0
 	expected := "https://api.weather.gov/points/40.000000,-75.000000"
-	got := buildForecastURL(lat, lon)
+	got := buildForecastURL(BASE_URL, lat, lon)
 	if got != expected {
 		t.Errorf("expected %s, got %s", expected, got)
 	}
@@ -85,6 +93,50 @@ func TestInitForecastAPI_ErrorStatus(t *testing.T) {
 	}
 }
 
+func TestInitForecastAPI_InvalidCoordinates(t *testing.T) {
+	lat, lon := 91.0, -75.0
+	client := newMockClient("{}", http.StatusOK)
+	api := NewWeatherGovAPI(client, &lat, &lon).(*weatherGovAPI)
+	err := api.InitForecastAPI(context.Background(), nil, nil)
+	if err == nil {
+		t.Fatal("expected error for out of range latitude")
+	}
+}
+
+func TestInitForecastAPI_OutsideCoverage(t *testing.T) {
+	lat, lon := 51.5, -0.12
+	mockBody := `{"title":"Data Unavailable For Requested Point","type":"https://api.weather.gov/problems/InvalidPoint","status":404,"detail":"Unable to provide data for requested point 51.5,-0.12"}`
+	client := newMockClient(mockBody, http.StatusNotFound)
+	api := NewWeatherGovAPI(client, &lat, &lon).(*weatherGovAPI)
+	err := api.InitForecastAPI(context.Background(), nil, nil)
+	var coverageErr *OutsideCoverageError
+	if !errors.As(err, &coverageErr) {
+		t.Fatalf("expected OutsideCoverageError, got %v", err)
+	}
+	if coverageErr.Latitude != lat || coverageErr.Longitude != lon {
+		t.Errorf("unexpected coordinates in error: %+v", coverageErr)
+	}
+}
+
+func TestValidateCoordinates(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		valid    bool
+	}{
+		{40.0, -75.0, true},
+		{-90, 180, true},
+		{90.1, 0, false},
+		{0, -180.5, false},
+		{math.NaN(), 0, false},
+	}
+	for _, tt := range tests {
+		err := ValidateCoordinates(tt.lat, tt.lon)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateCoordinates(%v, %v) = %v, want valid=%v", tt.lat, tt.lon, err, tt.valid)
+		}
+	}
+}
+
 func TestGetDailyForecast_Success(t *testing.T) {
 	lat, lon := 40.0, -75.0
 	forecastURL := "https://api.weather.gov/forecast"
@@ -167,13 +219,6 @@ func TestReadBody_InvalidJSON(t *testing.T) {
 	}
 }
 
-func TestHttpStatusError(t *testing.T) {
-	err := httpStatusError(404)
-	if err == nil || err.Error() != "received status code 404" {
-		t.Errorf("unexpected error: %v", err)
-	}
-}
-
 func TestPeriodJSONUnmarshal(t *testing.T) {
 	// Test that Period struct can unmarshal a minimal valid JSON
 	jsonStr := `{
@@ -205,3 +250,225 @@ func TestNewWeatherGovAPI(t *testing.T) {
 		t.Fatal("expected non-nil WeatherAPI")
 	}
 }
+
+func TestClientOptions(t *testing.T) {
+	var gotAgent, gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAgent = r.Header.Get("User-Agent")
+		gotPath = r.URL.Path
+		w.Write([]byte(`{"properties":{"forecast":"https://api.weather.gov/forecast"}}`))
+	}))
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	client := server.Client()
+	api := NewWeatherGovAPI(client, &lat, &lon,
+		WithUserAgent("roofmail-test (test@example.com)"),
+		WithBaseURL(server.URL+"/"),
+		WithTimeout(time.Second),
+		WithRateLimit(NewRateLimiter(100, 1)),
+	).(*weatherGovAPI)
+
+	err := api.InitForecastAPI(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAgent != "roofmail-test (test@example.com)" {
+		t.Errorf("unexpected User-Agent: %q", gotAgent)
+	}
+	if gotPath != "/points/40.000000,-75.000000" {
+		t.Errorf("unexpected path: %q", gotPath)
+	}
+	if api.client.Timeout != time.Second || client.Timeout != 0 {
+		t.Errorf("expected the timeout on a copy of the client, got %v and %v", api.client.Timeout, client.Timeout)
+	}
+
+	// without a client, the timeout goes on a default one
+	api = NewWeatherGovAPI(nil, &lat, &lon, WithTimeout(time.Second)).(*weatherGovAPI)
+	if api.client == nil || api.client.Timeout != time.Second {
+		t.Errorf("expected a default client with the timeout, got %+v", api.client)
+	}
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	var gotAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAgent = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"properties":{}}`))
+	}))
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	api := NewWeatherGovAPI(server.Client(), &lat, &lon, WithBaseURL(server.URL))
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAgent != DefaultUserAgent {
+		t.Errorf("expected default User-Agent, got %q", gotAgent)
+	}
+}
+
+func TestGetDailyForecast_ReresolvesMovedGridPoint(t *testing.T) {
+	var server *httptest.Server
+	var pointsCalls int
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/points/40.000000,-75.000000":
+			pointsCalls++
+			w.Header().Set("Cache-Control", "max-age=86400")
+			// the first lookup returns the old grid, later ones the new grid
+			grid := "OLD"
+			if pointsCalls > 1 {
+				grid = "NEW"
+			}
+			fmt.Fprintf(w, `{"properties":{"forecast":"%s/gridpoints/%s/1,1/forecast"}}`, server.URL, grid)
+		case "/gridpoints/NEW/1,1/forecast":
+			w.Write([]byte(`{"properties":{"units":"us","periods":[]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	api := NewWeatherGovAPI(server.Client(), &lat, &lon, WithBaseURL(server.URL), WithCache(NewMemoryCache())).(*weatherGovAPI)
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	forecast, err := api.GetDailyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if forecast.Units != "us" {
+		t.Errorf("expected units 'us', got %v", forecast.Units)
+	}
+	if pointsCalls != 2 {
+		t.Errorf("expected the point to be looked up twice despite the cache, got %d", pointsCalls)
+	}
+	if !strings.Contains(api.properties().Forecast, "/NEW/") {
+		t.Errorf("expected refreshed forecast URL, got %s", api.properties().Forecast)
+	}
+}
+
+func TestGetDailyForecast_PersistsReresolvedGridPoint(t *testing.T) {
+	for _, cacheControl := range []string{"max-age=86400", "no-store"} {
+		t.Run(cacheControl, func(t *testing.T) {
+			var server *httptest.Server
+			var pointsCalls int
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/points/41.000000,-74.000000":
+					pointsCalls++
+					// the first lookup is cached for a day, but NWS moves the point to a new grid
+					grid := "OLD"
+					w.Header().Set("Cache-Control", "max-age=86400")
+					if pointsCalls > 1 {
+						grid = "NEW"
+						w.Header().Set("Cache-Control", cacheControl)
+					}
+					fmt.Fprintf(w, `{"properties":{"forecast":"%s/gridpoints/%s/1,1/forecast"}}`, server.URL, grid)
+				case "/gridpoints/NEW/1,1/forecast":
+					w.Write([]byte(`{"properties":{"units":"us","periods":[]}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			cache, err := NewDiskCache(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the configured coordinates aren't the point the URLs are looked up for
+			configuredLat, configuredLon := 40.0, -75.0
+			lat, lon := 41.0, -74.0
+			api := NewWeatherGovAPI(server.Client(), &configuredLat, &configuredLon, WithBaseURL(server.URL), WithCache(cache))
+			if err := api.InitForecastAPI(context.Background(), &lat, &lon); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := api.GetDailyForecast(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pointsCalls != 2 {
+				t.Fatalf("expected the loaded point to be looked up again, got %d lookups", pointsCalls)
+			}
+
+			// starting again with the same cache directory finds the new grid
+			cache, err = NewDiskCache(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restarted := NewWeatherGovAPI(server.Client(), &lat, &lon, WithBaseURL(server.URL), WithCache(cache)).(*weatherGovAPI)
+			if err := restarted.InitForecastAPI(context.Background(), nil, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(restarted.properties().Forecast, "/NEW/") {
+				t.Errorf("expected the refreshed forecast URL after a restart, got %s", restarted.properties().Forecast)
+			}
+		})
+	}
+}
+
+func TestGetDailyForecast_NotFoundAfterReresolve(t *testing.T) {
+	var pointsCalls, forecastCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			pointsCalls++
+			w.Write([]byte(`{"properties":{"forecast":"` + "http://" + r.Host + `/forecast"}}`))
+			return
+		}
+		forecastCalls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	lat, lon := 40.0, -75.0
+	api := NewWeatherGovAPI(server.Client(), &lat, &lon, WithBaseURL(server.URL)).(*weatherGovAPI)
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := api.GetDailyForecast(context.Background())
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if pointsCalls != 2 || forecastCalls != 2 {
+		t.Errorf("expected a single retry, got %d points and %d forecast calls", pointsCalls, forecastCalls)
+	}
+}
+
+func TestReplayedFixtures(t *testing.T) {
+	lat, lon := 40.0, -75.0
+	client := &http.Client{Transport: httpfixture.NewReplayer("../fixtures")}
+	api := NewWeatherGovAPI(client, &lat, &lon)
+	if err := api.InitForecastAPI(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	daily, err := api.GetDailyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(daily.Periods) != 14 || daily.Periods[0].Name != "Today" {
+		t.Fatalf("unexpected daily forecast: %d periods", len(daily.Periods))
+	}
+	today := daily.Periods[0]
+	if today.Temperature == nil || today.Temperature.UnitCode != "wmoUnit:degC" {
+		t.Errorf("expected a quantitative temperature, got %+v", today.Temperature)
+	}
+	if today.WindSpeed == nil || today.WindSpeed.Value == nil || today.WindSpeed.UnitCode != "wmoUnit:km_h-1" {
+		t.Errorf("expected a quantitative wind speed, got %+v", today.WindSpeed)
+	}
+
+	hourly, err := api.GetHourlyForecast(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hourly.Periods) != 156 || hourly.Periods[0].RelativeHumidity == nil {
+		t.Errorf("unexpected hourly forecast: %d periods", len(hourly.Periods))
+	}
+}